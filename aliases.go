@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// renderAliases writes redirect pages for every alias of every page. Alias
+// must not match any other file written by this build, including other
+// aliases.
+func (b *builder) renderAliases() error {
+	for _, dir := range slices.Sorted(maps.Keys(b.dirsIndex)) {
+		for _, meta := range b.dirsIndex[dir].pages {
+			for _, alias := range meta.aliases {
+				dst := b.aliasPath(alias)
+				if _, ok := b.outputs[dst]; ok {
+					return fmt.Errorf("%s: alias %q collides with another output file %s", meta.src.path, alias, dst)
+				}
+				target, err := siteURL(b.args.BaseURL, b.sitePath(meta.dst, false), true)
+				if err != nil {
+					return err
+				}
+				out := new(bytes.Buffer)
+				if err := redirectTemplate.Execute(out, target); err != nil {
+					return err
+				}
+				if err := b.writeFile(dst, out.Bytes()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// aliasPath returns destination path for alias, which is a path relative to
+// the site root. Aliases ending with "/", or without file name extension are
+// treated as directories, and get index.html file within.
+func (b *builder) aliasPath(alias string) string {
+	p := path.Clean("/" + alias)
+	if strings.HasSuffix(alias, "/") || path.Ext(p) == "" {
+		p = path.Join(p, "index.html")
+	}
+	return filepath.Join(b.args.OutputDir, filepath.FromSlash(p))
+}
+
+var redirectTemplate = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.}}</title>
+<link rel="canonical" href="{{.}}">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url={{.}}">
+</head><body><a href="{{.}}">{{.}}</a></body></html>
+`))
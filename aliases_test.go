@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_builder_aliasPath(t *testing.T) {
+	out := filepath.FromSlash("/site/output")
+	b := &builder{args: runArgs{OutputDir: out}}
+	for alias, want := range map[string]string{
+		"/old/page.md":    "old/page.md",
+		"old/page.html":   "old/page.html",
+		"/old/dir/":       "old/dir/index.html",
+		"/old/dir":        "old/dir/index.html",
+		"../../etc/x.md":  "etc/x.md",
+		"/a/./b/../c.md/": "a/c.md/index.html",
+	} {
+		if got := b.aliasPath(alias); got != filepath.Join(out, filepath.FromSlash(want)) {
+			t.Errorf("aliasPath(%q): got %q, want %q", alias, got, want)
+		}
+	}
+}
+
+func Test_builder_renderAliases(t *testing.T) {
+	out := t.TempDir()
+	page := filepath.Join(out, "new", "page.md")
+	b := &builder{
+		args: runArgs{OutputDir: out},
+		dirsIndex: map[string]*dirIndex{
+			filepath.Dir(page): {pages: []pageMeta{{
+				src:     &mdSource{path: "page.md"},
+				dst:     page,
+				aliases: []string{"/old/page.md"},
+			}}},
+		},
+		outputs: map[string]struct{}{page: {}},
+	}
+	if err := b.renderAliases(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "old", "page.md")); err != nil {
+		t.Fatal(err)
+	}
+	// alias matching the file written before must fail the build
+	b.dirsIndex[filepath.Dir(page)].pages[0].aliases = []string{"/new/page.md"}
+	if err := b.renderAliases(); err == nil {
+		t.Fatal("alias colliding with another output file should be reported")
+	}
+}
@@ -12,9 +12,11 @@ import (
 
 // createAnchors takes utf-8 HTML data, parses it as a content of an <article>
 // element, walks over resulting tree and sets slugified unique id attribute
-// for each h1..h6 element it finds. It then renders such HTML subtree and
-// returns result. If reuse is true, input slice b is reused for rendering.
-func createAnchors(b []byte, reuse bool) ([]byte, error) {
+// for each h1..h6 element it finds, unless element already has a non-empty id,
+// which is kept as is. It then renders such HTML subtree and
+// returns result along with the table of contents built from headings found.
+// If reuse is true, input slice b is reused for rendering.
+func createAnchors(b []byte, reuse bool) ([]byte, []*tocEntry, error) {
 	root := &html.Node{
 		Type:     html.ElementNode,
 		DataAtom: atom.Article,
@@ -22,22 +24,23 @@ func createAnchors(b []byte, reuse bool) ([]byte, error) {
 	}
 	nodes, err := html.ParseFragment(bytes.NewReader(b), root)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, node := range nodes {
 		root.AppendChild(node)
 	}
 	seen := map[string]struct{}{}
+	var headings []*tocEntry
 	var walkFn func(*html.Node)
 	walkFn = func(n *html.Node) {
-		var isHeader bool
+		var level int
 		if n.Type == html.ElementNode {
 			switch n.DataAtom {
 			case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
-				isHeader = true
+				level = int(n.Data[1] - '0')
 			}
 		}
-		if !isHeader {
+		if level == 0 {
 			for c := n.FirstChild; c != nil; c = c.NextSibling {
 				walkFn(c)
 			}
@@ -46,6 +49,17 @@ func createAnchors(b []byte, reuse bool) ([]byte, error) {
 		// header node: it's ok to not recursively traverse children here, as
 		// headers cannot be nested
 		title := nodeText(n)
+		for _, attr := range n.Attr {
+			if attr.Key == "id" && attr.Val != "" {
+				seen[attr.Val] = struct{}{}
+				headings = append(headings, &tocEntry{
+					Level: level,
+					Text:  strings.TrimSpace(title),
+					ID:    attr.Val,
+				})
+				return
+			}
+		}
 
 		slug := anchor.Create(title)
 		if _, ok := seen[slug]; !ok {
@@ -60,6 +74,11 @@ func createAnchors(b []byte, reuse bool) ([]byte, error) {
 				}
 			}
 		}
+		headings = append(headings, &tocEntry{
+			Level: level,
+			Text:  strings.TrimSpace(title),
+			ID:    slug,
+		})
 		for i, attr := range n.Attr {
 			if attr.Key == "id" {
 				n.Attr[i].Val = slug
@@ -78,10 +97,39 @@ func createAnchors(b []byte, reuse bool) ([]byte, error) {
 	}
 	for c := root.FirstChild; c != nil; c = c.NextSibling {
 		if err := html.Render(out, c); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+	}
+	return out.Bytes(), nestHeadings(headings), nil
+}
+
+// tocEntry is an element of the page table of contents
+type tocEntry struct {
+	Level    int         // heading level, 1 for <h1>, 2 for <h2>, etc.
+	Text     string      // heading text
+	ID       string      // heading id attribute, to link as <a href="#{{.ID}}">
+	Children []*tocEntry // nested headings of higher levels
+}
+
+// nestHeadings takes a flat list of headings in document order and arranges
+// them into a tree, where each heading is nested under the closest preceding
+// heading of a lower level.
+func nestHeadings(headings []*tocEntry) []*tocEntry {
+	var out []*tocEntry
+	var stack []*tocEntry
+	for _, h := range headings {
+		for len(stack) != 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			out = append(out, h)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, h)
 		}
+		stack = append(stack, h)
 	}
-	return out.Bytes(), nil
+	return out
 }
 
 // nodeText returns text extracted from note and all its descendants
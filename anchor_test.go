@@ -4,7 +4,7 @@ import "testing"
 
 func Test_createAnchors(t *testing.T) {
 	const body = `<h1 class="foo">Some <span>header</span></h1><p>Text</p><h2>some header</h2>`
-	got, err := createAnchors([]byte(body), true)
+	got, toc, err := createAnchors([]byte(body), true)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -12,4 +12,23 @@ func Test_createAnchors(t *testing.T) {
 	if string(got) != want {
 		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
 	}
+	if len(toc) != 1 || toc[0].ID != "some-header" || len(toc[0].Children) != 1 ||
+		toc[0].Children[0].ID != "some-header-1" || toc[0].Children[0].Level != 2 {
+		t.Fatalf("unexpected table of contents: %+v", toc)
+	}
+}
+
+func Test_createAnchors_keepIDs(t *testing.T) {
+	const body = `<h2 id="api-v20">API v2.0</h2><h2>API</h2><h2>API</h2>`
+	got, toc, err := createAnchors([]byte(body), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `<h2 id="api-v20">API v2.0</h2><h2 id="api">API</h2><h2 id="api-1">API</h2>`
+	if string(got) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if len(toc) != 3 || toc[0].ID != "api-v20" {
+		t.Fatalf("unexpected table of contents: %+v", toc)
+	}
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Site holds site-wide values, available to every template as .Site
+type Site struct {
+	Title   string                // site title
+	BaseURL string                // URL of the site root
+	Params  map[string]any        // custom site parameters
+	Tree    *treeNode             // whole site navigation tree, root node is the site root
+	Tags    map[string][]pageMeta // pages grouped by their tags
+	Data    map[string]any        // content of data files, see loadData
+}
+
+// configNames are names of site configuration files in the source directory,
+// only the first existing one is used.
+var configNames = []string{"nothugo.toml", "nothugo.yaml", "nothugo.json"}
+
+// loadConfig reads the first existing site configuration file from dir, and
+// fills args with its values. Besides "title" and "params", config may have
+// keys named after flags from fset: such values are applied as if they were
+// set on the command line, unless the command line has them set explicitly;
+// relative "dst" and "templates" paths are relative to dir. If dir has no
+// configuration file, loadConfig does nothing.
+func loadConfig(args *runArgs, fset *flag.FlagSet, dir string) error {
+	var name string
+	var b []byte
+	for _, s := range configNames {
+		data, err := os.ReadFile(filepath.Join(dir, s))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		name, b = filepath.Join(dir, s), data
+		break
+	}
+	if name == "" {
+		return nil
+	}
+	var cfg map[string]any
+	var err error
+	switch filepath.Ext(name) {
+	case ".toml":
+		err = toml.Unmarshal(b, &cfg)
+	case ".yaml":
+		err = yaml.Unmarshal(b, &cfg)
+	case ".json":
+		err = json.Unmarshal(b, &cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", name, err)
+	}
+	explicit := make(map[string]bool)
+	fset.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for key, val := range cfg {
+		switch key {
+		case "title":
+			s, ok := val.(string)
+			if !ok {
+				return fmt.Errorf("%s: %q must be a string", name, key)
+			}
+			args.Title = s
+			continue
+		case "params":
+			m, ok := val.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: %q must be a table/object", name, key)
+			}
+			args.Params = m
+			continue
+		case "src":
+			return fmt.Errorf("%s: %q cannot be set in the configuration file", name, key)
+		}
+		f := fset.Lookup(key)
+		if f == nil {
+			return fmt.Errorf("%s: unknown key %q", name, key)
+		}
+		if explicit[key] {
+			continue
+		}
+		var s string
+		switch v := val.(type) {
+		case string, bool, int, int64, float64:
+			s = fmt.Sprint(v)
+		case time.Time: // TOML and YAML dates, as in "now = 2024-05-01"
+			s = v.Format(time.RFC3339)
+		default:
+			return fmt.Errorf("%s: unsupported value type for %q", name, key)
+		}
+		if (key == "dst" || key == "templates") && !filepath.IsAbs(s) {
+			s = filepath.Join(dir, s)
+		}
+		if err := f.Value.Set(s); err != nil {
+			return fmt.Errorf("%s: invalid value for %q: %w", name, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_loadConfig(t *testing.T) {
+	dir := t.TempDir()
+	const cfg = `title = "Docs"
+baseurl = "https://example.com/docs/"
+dst = "public"
+html = true
+now = 2024-05-01
+[params]
+repo = "https://example.com/repo"
+`
+	if err := os.WriteFile(filepath.Join(dir, "nothugo.toml"), []byte(cfg), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var args runArgs
+	fset := flag.NewFlagSet("", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	fset.StringVar(&args.OutputDir, "dst", "output", "")
+	fset.StringVar(&args.BaseURL, "baseurl", "", "")
+	fset.BoolVar(&args.SuffixHTML, "html", false, "")
+	fset.Func("now", "", func(s string) error {
+		if args.Now = toTime(s); args.Now.IsZero() {
+			return fmt.Errorf("cannot parse %q as a time", s)
+		}
+		return nil
+	})
+	if err := fset.Parse([]string{"-baseurl=https://example.org/"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(&args, fset, dir); err != nil {
+		t.Fatal(err)
+	}
+	if args.Title != "Docs" || args.Params["repo"] != "https://example.com/repo" {
+		t.Errorf("site values not set: %+v", args)
+	}
+	if want := filepath.Join(dir, "public"); args.OutputDir != want {
+		t.Errorf("got dst %q, want %q", args.OutputDir, want)
+	}
+	if !args.SuffixHTML {
+		t.Error("html flag not set from config")
+	}
+	if got := args.Now.Format(time.DateOnly); got != "2024-05-01" {
+		t.Errorf("got now %v, want 2024-05-01", args.Now)
+	}
+	if want := "https://example.org/"; args.BaseURL != want {
+		t.Errorf("command line value was overridden: got %q, want %q", args.BaseURL, want)
+	}
+}
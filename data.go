@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadData reads *.json, *.yaml (*.yml), and *.csv files from directory dir
+// into a nested map, keyed by subdirectory names and file names without
+// extensions, so "team/roster.yaml" file is available as
+// .Site.Data.team.roster. CSV files are loaded as lists of records, each
+// record being a list of fields. Files of other types are ignored. If dir does
+// not exist, loadData returns nil.
+func loadData(dir string) (map[string]any, error) {
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	data := make(map[string]any)
+	walkFunc := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if !slices.Contains(dataExts, ext) {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		v, err := decodeData(ext, b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		rel, err := filepath.Rel(dir, strings.TrimSuffix(path, ext))
+		if err != nil {
+			return err
+		}
+		m := data
+		keys := strings.Split(filepath.ToSlash(rel), "/")
+		for _, key := range keys[:len(keys)-1] {
+			switch sub := m[key].(type) {
+			case nil:
+				next := make(map[string]any)
+				m[key], m = next, next
+			case map[string]any:
+				m = sub
+			default:
+				return fmt.Errorf("%s: data key %q is already used by another file", path, key)
+			}
+		}
+		key := keys[len(keys)-1]
+		if _, ok := m[key]; ok {
+			return fmt.Errorf("%s: data key %q is already used by another file or directory", path, key)
+		}
+		m[key] = v
+		return nil
+	}
+	if err := filepath.WalkDir(dir, walkFunc); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeData decodes content b of the data file with extension ext.
+func decodeData(ext string, b []byte) (any, error) {
+	var v any
+	var err error
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(b, &v)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &v)
+	case ".csv":
+		v, err = csv.NewReader(bytes.NewReader(b)).ReadAll()
+	}
+	return v, err
+}
+
+const dataDir = "data" // source subdirectory with data files
+
+// dataExts are extensions of files loadData reads
+var dataExts = []string{".json", ".yaml", ".yml", ".csv"}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_loadData(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"services.json":      `[{"name": "api"}]`,
+		"team/roster.yaml":   "- name: Jane\n  role: lead\n",
+		"team/oncall.csv":    "week,name\n1,Jane\n",
+		"team/notes.txt":     "ignored",
+		".hidden/skip.json":  `{}`,
+		"team/.private.yaml": "x: 1",
+	} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := loadData(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"services": []any{map[string]any{"name": "api"}},
+		"team": map[string]any{
+			"roster": []any{map[string]any{"name": "Jane", "role": "lead"}},
+			"oncall": [][]string{{"week", "name"}, {"1", "Jane"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%#v\nwant:\n%#v", got, want)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "team.json"), []byte(`{}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadData(dir); err == nil {
+		t.Fatal("loading data with conflicting keys should fail")
+	}
+}
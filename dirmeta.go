@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dirMeta is a content of the optional per-directory metadata file
+type dirMeta struct {
+	Title       string `yaml:"title"`       // directory title, instead of the one derived from its name
+	Description string `yaml:"description"` // directory description, shown in the parent index
+	Weight      int    `yaml:"weight"`      // position among subdirectories of the parent, see sortPages
+	Sort        string `yaml:"sort"`        // order of pages in this directory, overrides -sort flag
+	Hidden      bool   `yaml:"hidden"`      // whether to omit directory from its parent index
+}
+
+// readDirMeta reads metadata file from the source directory dir. If dir has
+// no such file, it returns nil.
+func readDirMeta(dir string) (*dirMeta, error) {
+	name := filepath.Join(dir, dirMetaName)
+	b, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := new(dirMeta)
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(meta); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if meta.Sort != "" {
+		if _, _, err := parseSortOrder(meta.Sort); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return meta, nil
+}
+
+const dirMetaName = "_dir.yaml"
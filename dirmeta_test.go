@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_readDirMeta(t *testing.T) {
+	dir := t.TempDir()
+	if meta, err := readDirMeta(dir); err != nil || meta != nil {
+		t.Fatalf("directory without metadata file: got %v, %v", meta, err)
+	}
+	const content = "title: API v2\nweight: 3\nsort: title,reverse\nhidden: true\n"
+	if err := os.WriteFile(filepath.Join(dir, dirMetaName), []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := readDirMeta(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dirMeta{Title: "API v2", Weight: 3, Sort: "title,reverse", Hidden: true}
+	if *meta != want {
+		t.Fatalf("got %+v, want %+v", *meta, want)
+	}
+	if err := os.WriteFile(filepath.Join(dir, dirMetaName), []byte("titel: typo\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDirMeta(dir); err == nil {
+		t.Fatal("unknown keys should be reported")
+	}
+}
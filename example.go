@@ -41,6 +41,12 @@ const examplesRoot = "example-content"
 //go:embed example-template.html
 var exampleTemplate []byte
 
+// exampleTemplateFS holds the same template as exampleTemplate, it is used
+// when the templates directory does not exist
+//
+//go:embed example-template.html
+var exampleTemplateFS embed.FS
+
 // writeIfNotExists creates file at dst and writes b as its content. It fails
 // if file already exists. Parent directories created as necessary.
 func writeIfNotExists(dst string, b []byte) error {
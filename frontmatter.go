@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// splitFrontMatter checks whether b starts with a front matter block, either
+// YAML one delimited by "---" lines, or TOML one delimited by "+++" lines. If
+// such block is found, it is parsed and returned as a map along with the rest
+// of b that follows the block. If b has no front matter, or its opening line
+// has no matching closing one (a "---" line may be a Markdown thematic break),
+// function returns nil map and b as is.
+func splitFrontMatter(b []byte) (map[string]any, []byte, error) {
+	var delim []byte
+	switch {
+	case bytes.HasPrefix(b, yamlDelim):
+		delim = yamlDelim
+	case bytes.HasPrefix(b, tomlDelim):
+		delim = tomlDelim
+	default:
+		return nil, b, nil
+	}
+	line, rest, ok := bytes.Cut(b, []byte("\n"))
+	if !ok || !bytes.Equal(bytes.TrimRight(line, " \t\r"), delim) {
+		return nil, b, nil
+	}
+	var block []byte
+	for off := 0; ; {
+		line, tail, found := bytes.Cut(rest[off:], []byte("\n"))
+		if bytes.Equal(bytes.TrimRight(line, " \t\r"), delim) {
+			block, rest = rest[:off], tail
+			break
+		}
+		if !found {
+			return nil, b, nil
+		}
+		off += len(line) + 1
+	}
+	params := make(map[string]any)
+	var err error
+	if bytes.Equal(delim, yamlDelim) {
+		err = yaml.Unmarshal(block, &params)
+	} else {
+		err = toml.Unmarshal(block, &params)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing front matter: %w", err)
+	}
+	return params, rest, nil
+}
+
+var (
+	yamlDelim = []byte("---")
+	tomlDelim = []byte("+++")
+)
+
+// paramString returns value of the key from params if it is a string, or an
+// empty string otherwise.
+func paramString(params map[string]any, key string) string {
+	s, _ := params[key].(string)
+	return s
+}
+
+// paramStrings returns value of the key from params as a list of strings.
+// Value may be either a list, or a single string. Non-string list elements
+// and empty strings are ignored.
+func paramStrings(params map[string]any, key string) []string {
+	var out []string
+	switch v := params[key].(type) {
+	case string:
+		if v != "" {
+			out = append(out, v)
+		}
+	case []any:
+		for _, elem := range v {
+			if s, ok := elem.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// paramInt returns value of the key from params if it is a number, or zero
+// otherwise. Fractional part of floating point numbers is discarded.
+func paramInt(params map[string]any, key string) int {
+	switch v := params[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// paramTime returns value of the key from params as a time, see toTime.
+func paramTime(params map[string]any, key string) time.Time {
+	return toTime(params[key])
+}
+
+// toTime interprets v as a time. Both native YAML/TOML dates and strings in a
+// handful of common layouts are supported. If v cannot be interpreted as a
+// time, zero time is returned.
+func toTime(v any) time.Time {
+	switch v := v.(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
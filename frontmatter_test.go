@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_splitFrontMatter(t *testing.T) {
+	for _, tc := range []struct {
+		name, src string
+	}{
+		{"yaml", "---\ntitle: Hello\ndate: 2024-01-02\n---\n# Body\n"},
+		{"toml", "+++\ntitle = \"Hello\"\ndate = 2024-01-02\n+++\n# Body\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			params, body, err := splitFrontMatter([]byte(tc.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(body), "# Body\n"; got != want {
+				t.Fatalf("got body %q, want %q", got, want)
+			}
+			if got, want := paramString(params, "title"), "Hello"; got != want {
+				t.Fatalf("got title %q, want %q", got, want)
+			}
+			if got := paramTime(params, "date"); got.Format(time.DateOnly) != "2024-01-02" {
+				t.Fatalf("got date %v", got)
+			}
+		})
+	}
+	for _, plain := range []string{
+		"# Header\n\n---\n",
+		"---\n\nText after a thematic break",
+	} {
+		params, body, err := splitFrontMatter([]byte(plain))
+		if err != nil || params != nil || string(body) != plain {
+			t.Fatalf("file without front matter: got %v, %q, %v", params, body, err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	anchor "github.com/shurcooL/sanitized_anchor_name"
+)
+
+// templateFuncs returns functions available to templates in addition to the
+// text/template builtins, see package documentation for their description.
+// Functions use args for the site base URL and source directory, and convert
+// for the markdownify function.
+func templateFuncs(args runArgs, convert convertFunc) template.FuncMap {
+	return template.FuncMap{
+		"relURL":     func(p string) (string, error) { return siteURL(args.BaseURL, p, false) },
+		"absURL":     func(p string) (string, error) { return siteURL(args.BaseURL, p, true) },
+		"dateFormat": dateFormat,
+		"truncate":   truncate,
+		"markdownify": func(s string) (template.HTML, error) {
+			var buf bytes.Buffer
+			if err := convert(&buf, strings.NewReader(s)); err != nil {
+				return "", err
+			}
+			return template.HTML(buf.String()), nil
+		},
+		"safeHTML": func(s string) template.HTML { return template.HTML(s) },
+		"urlize":   anchor.Create,
+		"readFile": func(name string) (string, error) {
+			b, err := fs.ReadFile(os.DirFS(args.InputDir), path.Clean(strings.TrimPrefix(name, "/")))
+			return string(b), err
+		},
+		"sort":  sortList,
+		"first": firstN,
+		"where": where,
+	}
+}
+
+// siteURL returns p, which is a path relative to the site root, prefixed with
+// the base URL. If abs is false, only the path part of the base URL is used.
+// Absolute URLs are returned as is.
+func siteURL(base, p string, abs bool) (string, error) {
+	if u, err := url.Parse(p); err == nil && u.IsAbs() {
+		return p, nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(p, "/")
+	if !abs || u.Host == "" {
+		return s, nil
+	}
+	u.Path, u.RawPath = s, ""
+	return u.String(), nil
+}
+
+// dateFormat formats time v, which may also be a date string, with Go time
+// layout. It returns an empty string for zero times and values it cannot
+// interpret, so templates can use it with optional dates, such as Page.Date.
+func dateFormat(layout string, v any) string {
+	t := toTime(v)
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// truncate returns s shortened to at most n runes. If s had to be cut, the
+// last rune of the result is "…".
+func truncate(n int, s string) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	s = strings.TrimSpace(string([]rune(s)[:n-1]))
+	return s + "…"
+}
+
+// sortList returns a copy of list sorted in ascending order. If key is given,
+// elements are compared by their field or map key with such name. If the last
+// of args is "desc", order is reversed.
+func sortList(list any, args ...string) (any, error) {
+	v, err := listValue(list)
+	if err != nil {
+		return nil, err
+	}
+	var key string
+	var desc bool
+	if len(args) > 0 && (args[len(args)-1] == "desc" || args[len(args)-1] == "asc") {
+		desc = args[len(args)-1] == "desc"
+		args = args[:len(args)-1]
+	}
+	switch len(args) {
+	case 0:
+	case 1:
+		key = args[0]
+	default:
+		return nil, errors.New("sort: too many arguments")
+	}
+	out := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+	reflect.Copy(out, v)
+	keyOf := func(i int) reflect.Value {
+		if key == "" {
+			return out.Index(i)
+		}
+		k, _ := fieldValue(out.Index(i), key)
+		return k
+	}
+	sort.SliceStable(out.Interface(), func(i, j int) bool {
+		if desc {
+			return lessValue(keyOf(j), keyOf(i))
+		}
+		return lessValue(keyOf(i), keyOf(j))
+	})
+	return out.Interface(), nil
+}
+
+// firstN returns the first n elements of list.
+func firstN(n int, list any) (any, error) {
+	v, err := listValue(list)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("first: negative count %d", n)
+	}
+	return v.Slice(0, min(n, v.Len())).Interface(), nil
+}
+
+// where returns elements of list which field or map key named key is equal to
+// value.
+func where(list any, key string, value any) (any, error) {
+	v, err := listValue(list)
+	if err != nil {
+		return nil, err
+	}
+	out := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), 0, v.Len())
+	want := reflect.ValueOf(value)
+	for i := range v.Len() {
+		if f, ok := fieldValue(v.Index(i), key); ok && equalValues(f, want) {
+			out = reflect.Append(out, v.Index(i))
+		}
+	}
+	return out.Interface(), nil
+}
+
+// listValue returns list as a reflect.Value of slice or array kind.
+func listValue(list any) (reflect.Value, error) {
+	v := reflect.ValueOf(list)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v, nil
+	}
+	return reflect.Value{}, fmt.Errorf("expected a list, got %T", list)
+}
+
+// fieldValue returns exported struct field or a map value named key of v,
+// dereferencing pointers and interfaces as necessary.
+func fieldValue(v reflect.Value, key string) (reflect.Value, bool) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		if f, ok := v.Type().FieldByName(key); ok && f.IsExported() {
+			return indirect(v.FieldByIndex(f.Index)), true
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if f := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())); f.IsValid() {
+			return indirect(f), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// lessValue reports whether a sorts before b. Numbers, strings, and times are
+// compared naturally, invalid values sort first, anything else is compared by
+// its string representation.
+func lessValue(a, b reflect.Value) bool {
+	a, b = indirect(a), indirect(b)
+	switch {
+	case !a.IsValid():
+		return b.IsValid()
+	case !b.IsValid():
+		return false
+	}
+	if x, ok := numberValue(a); ok {
+		if y, ok := numberValue(b); ok {
+			return x < y
+		}
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return a.String() < b.String()
+	}
+	if x, ok := a.Interface().(time.Time); ok {
+		if y, ok := b.Interface().(time.Time); ok {
+			return x.Before(y)
+		}
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// equalValues reports whether a and b are equal, treating numbers of
+// different types as equal if they have the same value.
+func equalValues(a, b reflect.Value) bool {
+	a, b = indirect(a), indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if x, ok := numberValue(a); ok {
+		if y, ok := numberValue(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func numberValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_siteURL(t *testing.T) {
+	for _, tc := range []struct {
+		base, p string
+		abs     bool
+		want    string
+	}{
+		{"", "docs/", false, "/docs/"},
+		{"", "/docs/", true, "/docs/"},
+		{"https://example.com/site/", "docs/a.md", false, "/site/docs/a.md"},
+		{"https://example.com/site", "/docs/a.md", true, "https://example.com/site/docs/a.md"},
+		{"https://example.com/", "https://other.example.com/", true, "https://other.example.com/"},
+	} {
+		got, err := siteURL(tc.base, tc.p, tc.abs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("siteURL(%q, %q, %v): got %q, want %q", tc.base, tc.p, tc.abs, got, tc.want)
+		}
+	}
+}
+
+func Test_dateFormat(t *testing.T) {
+	tpl := template.Must(template.New("").Funcs(templateFuncs(runArgs{}, nil)).Parse(
+		`[{{.Date | dateFormat "Jan 2, 2006"}}]`))
+	for _, tc := range []struct {
+		page *Page
+		want string
+	}{
+		{&Page{Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}, "[May 1, 2024]"},
+		{&Page{}, "[]"},
+	} {
+		var sb strings.Builder
+		if err := tpl.Execute(&sb, tc.page); err != nil {
+			t.Fatal(err)
+		}
+		if got := sb.String(); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+	if got := dateFormat(time.DateOnly, "not a date"); got != "" {
+		t.Errorf("got %q for unparseable date, want empty string", got)
+	}
+	if got, want := dateFormat(time.DateOnly, "2024-05-01T10:00:00Z"), "2024-05-01"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func Test_truncate(t *testing.T) {
+	if got, want := truncate(6, "Hello, world"), "Hello…"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := truncate(20, "Hello, world"), "Hello, world"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func Test_collectionFuncs(t *testing.T) {
+	pages := []pageMeta{{Title: "b", Dst: "2"}, {Title: "c", Dst: "1"}, {Title: "a", Dst: "1"}}
+	got, err := sortList(pages, "Title", "desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pageMeta{{Title: "c", Dst: "1"}, {Title: "b", Dst: "2"}, {Title: "a", Dst: "1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sort: got %v, want %v", got, want)
+	}
+	if got, err = where(pages, "Dst", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := pages[1:]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("where: got %v, want %v", got, want)
+	}
+	if got, err = firstN(1, pages); err != nil {
+		t.Fatal(err)
+	}
+	if want := pages[:1]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("first: got %v, want %v", got, want)
+	}
+	params := []map[string]any{{"weight": int64(2)}, {"weight": 1}}
+	if got, err = sortList(params, "weight"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]any{{"weight": 1}, {"weight": int64(2)}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sort maps: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitCommit describes the last git commit that changed a file
+type gitCommit struct {
+	Hash   string    // full commit hash
+	Author string    // author name
+	Date   time.Time // author date
+}
+
+// gitRepo describes git work tree that a directory belongs to
+type gitRepo struct {
+	prefix  string                // slash-separated directory path relative to the work tree root, empty or ending with slash
+	branch  string                // current branch name, or "HEAD" if detached
+	commits map[string]*gitCommit // last commits of files in directory, see parseGitLog
+}
+
+// readGitRepo returns details of git work tree that directory dir belongs to,
+// including the last commit for each file under dir that is tracked by git,
+// keyed by file path joined with dir. It runs a single "git log" over the
+// whole history. If dir is not inside a git work tree with at least one
+// commit, or git binary is not available, it returns nil.
+func readGitRepo(dir string) (*gitRepo, error) {
+	if _, err := exec.LookPath(gitBinary); err != nil {
+		return nil, nil
+	}
+	cmd := exec.Command(gitBinary, "rev-parse", "--show-prefix", "--abbrev-ref", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, nil
+	}
+	lines := strings.Split(string(out), "\n")
+	if len(lines) < 2 {
+		return nil, nil
+	}
+	repo := &gitRepo{prefix: lines[0], branch: lines[1]}
+	cmd = exec.Command(gitBinary, "-c", "core.quotePath=false", "log",
+		"--relative", "--name-only", "--no-renames",
+		"--format="+gitRecordSep+"%H"+gitFieldSep+"%aI"+gitFieldSep+"%an",
+		"--", ".")
+	cmd.Dir = dir
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	if out, err = cmd.Output(); err != nil {
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			return nil, errors.New(s)
+		}
+		return nil, err
+	}
+	repo.commits = parseGitLog(dir, string(out))
+	return repo, nil
+}
+
+// parseGitLog parses output of the "git log" command run by readGitRepo.
+// Commits are expected to be in reverse chronological order, so the first
+// commit listing a file is the last one that changed it.
+func parseGitLog(dir, out string) map[string]*gitCommit {
+	commits := make(map[string]*gitCommit)
+	for _, rec := range strings.Split(out, gitRecordSep) {
+		header, names, _ := strings.Cut(rec, "\n")
+		fields := strings.Split(header, gitFieldSep)
+		if len(fields) != 3 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			continue
+		}
+		c := &gitCommit{Hash: fields[0], Date: date, Author: fields[2]}
+		for _, name := range strings.Split(names, "\n") {
+			if name == "" {
+				continue
+			}
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if _, ok := commits[path]; !ok {
+				commits[path] = c
+			}
+		}
+	}
+	return commits
+}
+
+const (
+	gitBinary    = "git"
+	gitRecordSep = "\x1e"
+	gitFieldSep  = "\x1f"
+)
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func Test_parseGitLog(t *testing.T) {
+	const out = "\x1eaaa\x1f2024-05-02T10:00:00+02:00\x1fJane Doe\n\nb.md\ndir/c.md\n" +
+		"\x1ebbb\x1f2024-05-01T10:00:00Z\x1fJohn Doe\n\na.md\nb.md\n"
+	commits := parseGitLog("src", out)
+	for name, want := range map[string]string{"a.md": "bbb", "b.md": "aaa", "dir/c.md": "aaa"} {
+		c := commits[filepath.Join("src", name)]
+		if c == nil {
+			t.Errorf("no commit for %s", name)
+			continue
+		}
+		if c.Hash != want {
+			t.Errorf("%s: got commit %s, want %s", name, c.Hash, want)
+		}
+	}
+	if c := commits[filepath.Join("src", "b.md")]; c.Author != "Jane Doe" || c.Date.Day() != 2 {
+		t.Errorf("unexpected commit: %+v", c)
+	}
+	if len(commits) != 3 {
+		t.Errorf("got %d commits, want 3", len(commits))
+	}
+}
@@ -3,7 +3,9 @@ module github.com/artyom/nothugo
 go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/shurcooL/sanitized_anchor_name v1.0.0
 	github.com/yuin/goldmark v1.7.12
 	golang.org/x/net v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -15,10 +15,140 @@
 // Non-regular files, or files/directories with names starting with "." (unix
 // hidden) are skipped.
 //
+// A *.md file may start with a front matter block: either YAML one, delimited
+// by "---" lines, or TOML one, delimited by "+++" lines. Its values are
+// available to templates, "title", "date", and "description" keys have special
+// meaning: title set this way takes precedence over the first page heading.
+//
+// Pages with "draft: true" in their front matter, or with names ending with
+// ".draft.md" are drafts: they are skipped unless -drafts flag is set.
+//
+// Pages with "publishDate" (or "date", if the former is not set) front matter
+// value in the future, or with "expiryDate" value in the past are skipped too.
+// Use -now flag to see how the site will look at some other time.
+//
+// Each *.html file in the templates directory (-templates) is a separate
+// template named after its file. Pages are rendered with "page.html" template,
+// and directory indexes with "index.html" one; if either is missing,
+// "default.html" is used, or the first template if there's no such file
+// either. A page may explicitly pick its template by name with "template"
+// front matter key; for directory index this key is read from its README.md.
+// A source directory may have its own "_template.html" file, overriding both
+// page and index templates for this directory and all its subdirectories,
+// unless they have their own "_template.html" file. If the templates directory
+// does not exist, the built-in template is used, the same one the "example"
+// mode writes.
+//
+// Templates from the "partials" subdirectory of the templates directory can be
+// used by all other templates, as in {{template "header.html" .}}. If the
+// templates directory has "baseof.html" file, it is a base layout for all other
+// templates there ("page.html", "index.html", "tag.html", etc.), and for
+// "_template.html" files: these only need to define blocks the base layout
+// declares with {{block "main" .}}...{{end}}. Templates referring to missing
+// templates or blocks are reported before rendering.
+//
+// Pages may use shortcodes to embed HTML snippets rendered with templates from
+// the "shortcodes" subdirectory of the templates directory. Shortcode
+// {{< figure src="cat.png" >}}A cat{{< /figure >}} is rendered with
+// "shortcodes/figure.html" template, which gets arguments with {{.Get "src"}}
+// (or {{.Get 0}} for positional ones), and raw text between tags as {{.Inner}};
+// shortcodes without closing tag have empty .Inner. To show shortcode as is,
+// write it as {{</* figure */>}}.
+//
+// Pages in directory indexes, and subdirectories are ordered by name (numeric
+// prefixes, as in "01-intro.md", are compared as numbers and removed from
+// titles derived from file names), or as set by the -sort flag. Pages with
+// "weight" front matter value come first, in the order of increasing weight.
+//
+// Each source directory may have a "_dir.yaml" metadata file, with its title,
+// description, weight (position among other subdirectories), sort (order of
+// its pages, overrides -sort flag), and hidden (whether to omit this directory
+// from its parent index) values:
+//
+//	title: API v2
+//	weight: 10
+//	sort: date,reverse
+//
+// Pages may have "tags" front matter value, a list of tags. For each tag there
+// is a "tags/TAG/index.html" page listing all pages with this tag, and
+// "tags/index.html" page lists all tags. These pages use "tag.html" and
+// "tags.html" templates respectively, or fall back to "index.html".
+//
+// Directory indexes may show short summaries of their pages: either their
+// "description" front matter values, or a text before the "<!--more-->" line,
+// or the first paragraph of a page.
+//
+// Both pages and index entries have word count and estimated reading time,
+// computed from the page text without code blocks.
+//
+// When source directory is inside a git work tree, commit hash, author, and
+// date of the last commit that changed each page are available to templates.
+// Since git does not preserve file modification times, last commit dates are
+// used instead, both for sorting and for modification times of output files.
+// The same goes for template files tracked in a git work tree; only untracked
+// files contribute their own modification times.
+//
+// With -editurl flag each page and README-based index gets a link to edit its
+// source: "{path}" in the pattern is replaced with the source file path
+// relative to the git work tree root, and "{branch}" with the current branch
+// or -branch flag value. Set -branch when building from a detached HEAD, or
+// outside of a git work tree: rendering fails if the branch is not known.
+//
+// When pages are moved, their old locations can be listed in "aliases" front
+// matter value, as paths relative to the site root, such as "/old/page.md" or
+// "/old/dir/". For each alias a small HTML page redirecting to the new location
+// is created; it is an error for an alias to match any other output file.
+//
+// If the templates directory has "404.html" template, or the source directory
+// has "404.md" page, "404.html" page is created at the output root, to be
+// shown for missing pages. Page from "404.md" is rendered with "404.html"
+// template, or as any other page if there's no such template; it is not listed
+// in indexes. Since this page is shown at arbitrary paths, its links should be
+// site-root-relative, as made by relURL.
+//
+// Files from the "data" subdirectory of the source directory are not copied to
+// the output, instead JSON, YAML, and CSV files from there are available to
+// templates as .Site.Data, keyed by subdirectory and file names without
+// extensions: "data/team/roster.yaml" is {{.Site.Data.team.roster}}. CSV files
+// are lists of records, each record being a list of fields.
+//
+// Source directory may have a site configuration file: nothugo.toml,
+// nothugo.yaml, or nothugo.json. Its "title" and "params" values are available
+// to templates as .Site.Title and .Site.Params, other keys are named after
+// command line flags and set their defaults, for example:
+//
+//	title = "Documentation"
+//	baseurl = "https://example.com/docs/"
+//	html = true
+//	[params]
+//	repo = "https://github.com/example/docs"
+//
+// Besides the text/template builtins, templates can use these functions:
+//
+//	relURL PATH            PATH relative to the site root, prefixed with the
+//	                       path of -baseurl: "docs/" → "/docs/"
+//	absURL PATH            PATH relative to the site root, prefixed with
+//	                       -baseurl: "docs/" → "https://example.com/docs/"
+//	dateFormat LAYOUT T    time T (or a date string) formatted with Go time
+//	                       layout: {{.Date | dateFormat "Jan 2, 2006"}}, or
+//	                       an empty string if T is not set
+//	truncate N S           S shortened to at most N characters, with "…"
+//	                       appended if it was cut
+//	markdownify S          S converted from Markdown to HTML
+//	safeHTML S             S marked as safe HTML, so it's not escaped
+//	urlize S               S converted to a form suitable for URL path, the
+//	                       same way tag names are: "Go Tips" → "go-tips"
+//	readFile NAME          content of the file NAME from the source directory
+//	sort LIST [KEY] [desc] copy of LIST sorted by its elements, or by the KEY
+//	                       field/map key of each element
+//	first N LIST           the first N elements of LIST
+//	where LIST KEY VALUE   elements of LIST which KEY field/map key is VALUE
+//
 // serve:
 //
 // In this mode program starts basic HTTP server (-addr) serving static files
-// from the output directory (-dst). It is not the only way to serve generated
+// from the output directory (-dst). Requests for missing files get the
+// "404.html" page, if there's one. It is not the only way to serve generated
 // content, this can be done with any web server. Most useful for local
 // previews.
 //
@@ -40,12 +170,15 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	"maps"
 	"mime"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -60,16 +193,37 @@ func main() {
 		OutputDir:    "output",
 		TemplatesDir: "templates",
 		Addr:         "localhost:8080",
+		Sort:         "name",
 	}
 	flag.StringVar(&args.InputDir, "src", args.InputDir, "source directory with .md files")
 	flag.StringVar(&args.OutputDir, "dst", args.OutputDir, "destination directory to write rendered files")
 	flag.StringVar(&args.TemplatesDir, "templates", args.TemplatesDir, "directory with .html templates")
 	flag.StringVar(&args.Addr, "addr", args.Addr, "host:port to listen when run in serve mode")
+	flag.StringVar(&args.BaseURL, "baseurl", args.BaseURL, "URL of the site root, as in `https://example.com/docs/`")
 	flag.BoolVar(&args.SuffixHTML, "html", false, "save rendered files with .html suffix instead of .md")
+	flag.BoolVar(&args.Drafts, "drafts", false, "render draft pages too, useful for local previews")
+	flag.Func("now", "reference `time` to check page publish and expiry dates against,\ne.g. 2024-05-01 or 2024-05-01T10:00:00Z; current time by default", func(s string) error {
+		if args.Now = toTime(s); args.Now.IsZero() {
+			return fmt.Errorf("cannot parse %q as a time", s)
+		}
+		return nil
+	})
+	flag.StringVar(&args.Sort, "sort", args.Sort, "order of pages in indexes: name, title, date, or mtime,\noptionally followed by \",reverse\"")
+	flag.StringVar(&args.EditURL, "editurl", args.EditURL, "URL `pattern` to edit page source, with {branch} and {path} placeholders,\n"+
+		"e.g. https://github.com/user/repo/edit/{branch}/{path}")
+	flag.StringVar(&args.Branch, "branch", args.Branch, "git branch for the -editurl pattern, current one by default")
 	flag.Parse()
 	log.SetFlags(0)
 	var err error
 	switch flag.Arg(0) {
+	case "serve", "render":
+		err = loadConfig(&args, flag.CommandLine, args.InputDir)
+	}
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	switch flag.Arg(0) {
 	case "serve":
 		_ = mime.AddExtensionType(".md", "text/html") // override local mime db
 		err = serve(args.Addr, args.OutputDir)
@@ -91,8 +245,17 @@ type runArgs struct {
 	InputDir     string
 	OutputDir    string
 	TemplatesDir string
-	Addr         string // only for serve
-	SuffixHTML   bool   // whether to create destination files with .html suffix
+	Addr         string    // only for serve
+	BaseURL      string    // URL of the site root, used by relURL and absURL template functions
+	SuffixHTML   bool      // whether to create destination files with .html suffix
+	Drafts       bool      // whether to render draft pages
+	Sort         string    // order of pages in directory indexes, see sortKeys
+	Now          time.Time // reference time to decide whether pages are published, zero means current time
+	EditURL      string    // URL pattern to edit page source, see editURL
+	Branch       string    // git branch for EditURL, current branch if empty
+
+	Title  string         // site title, only set from the config file
+	Params map[string]any // custom site parameters, only set from the config file
 }
 
 func (args *runArgs) validate() error {
@@ -112,6 +275,15 @@ func (args *runArgs) validate() error {
 	if args.InputDir == args.TemplatesDir {
 		return errors.New("source and templates directories cannot be the same")
 	}
+	if _, err := url.Parse(args.BaseURL); err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+	if _, _, err := parseSortOrder(args.Sort); err != nil {
+		return err
+	}
+	if args.EditURL != "" && !strings.Contains(args.EditURL, "{path}") {
+		return errors.New("edit URL pattern must have {path} placeholder")
+	}
 	return nil
 }
 
@@ -119,15 +291,6 @@ func run(args runArgs) error {
 	if err := args.validate(); err != nil {
 		return err
 	}
-	tpl, err := template.ParseFS(os.DirFS(args.TemplatesDir), "*.html")
-	if err != nil {
-		return fmt.Errorf("parsing templates from %q: %w", args.TemplatesDir, err)
-	}
-	mtime, err := latestMtime(os.DirFS(args.TemplatesDir), "*.html")
-	if err != nil {
-		return err
-	}
-
 	md := goldmark.New(
 		goldmark.WithExtensions(extension.GFM),
 		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
@@ -142,16 +305,72 @@ func run(args runArgs) error {
 	if _, err := exec.LookPath(gfmBinary); err == nil {
 		convert = cmarkConvert
 	}
-
-	// used to build index.html files. Key is a *destination* directory.
-	dirsIndex := make(map[string]struct {
-		pages      []pageMeta // pages in this directory
-		categories []pageMeta // subdirectories that contain .md files
-	})
+	tplFS := os.DirFS(args.TemplatesDir)
+	if _, err := os.Stat(args.TemplatesDir); errors.Is(err, fs.ErrNotExist) {
+		log.Printf("templates directory %q does not exist, using the built-in default template", args.TemplatesDir)
+		tplFS = exampleTemplateFS
+	}
+	tpl, err := loadTemplates(tplFS, args.InputDir, templateFuncs(args, convert))
+	if err != nil {
+		return fmt.Errorf("parsing templates from %q: %w", args.TemplatesDir, err)
+	}
+	repo, err := readGitRepo(args.InputDir)
+	if err != nil {
+		return fmt.Errorf("reading git history of %q: %w", args.InputDir, err)
+	}
+	var commits map[string]*gitCommit
+	if repo != nil {
+		commits = repo.commits
+	}
+	tpl.commits = commits
+	tplRepo, err := readGitRepo(args.TemplatesDir)
+	if err != nil {
+		return fmt.Errorf("reading git history of %q: %w", args.TemplatesDir, err)
+	}
+	if tplRepo != nil {
+		if tpl.mtime, err = templatesMtime(tplFS, args.TemplatesDir, tplRepo.commits); err != nil {
+			return err
+		}
+	}
+	site := &Site{
+		Title:   args.Title,
+		BaseURL: args.BaseURL,
+		Params:  args.Params,
+	}
+	b := &builder{
+		args:      args,
+		tpls:      tpl,
+		convert:   convert,
+		repo:      repo,
+		site:      site,
+		dirsIndex: make(map[string]*dirIndex),
+		outputs:   make(map[string]struct{}),
+	}
+	if err := b.checkEditURL(); err != nil {
+		return err
+	}
+	now := args.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
 	// directories that already have "index.html" file in them, to avoid
 	// overwriting them with automatically generated index. Key is a
 	// *destination* directory.
 	skipIndex := make(map[string]struct{})
+	var notFound *mdSource // source of the "page not found" page, if any
+	// subdirectories read their metadata files once registered as categories
+	// below, the root directory is never one
+	rootMeta, err := readDirMeta(args.InputDir)
+	if err != nil {
+		return err
+	}
+	if rootMeta != nil {
+		b.index(args.OutputDir).meta = rootMeta
+	}
+	dataPath := filepath.Join(args.InputDir, dataDir)
+	if site.Data, err = loadData(dataPath); err != nil {
+		return err
+	}
 
 	walkFunc := func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -162,7 +381,7 @@ func run(args runArgs) error {
 			// skip hidden directories
 			return filepath.SkipDir
 		}
-		if d.IsDir() && (path == args.TemplatesDir || path == args.OutputDir) {
+		if d.IsDir() && (path == args.TemplatesDir || path == args.OutputDir || path == dataPath) {
 			return filepath.SkipDir
 		}
 		if !d.Type().IsRegular() || strings.HasPrefix(base, ".") {
@@ -175,29 +394,71 @@ func run(args runArgs) error {
 		}
 		dst := filepath.Join(args.OutputDir, rel)
 
+		var src *mdSource
+		if strings.HasSuffix(path, mdSuffix) {
+			if src, err = readSource(path); err != nil {
+				return err
+			}
+			if c := commits[path]; c != nil {
+				src.commit = c
+				src.mtime = c.Date
+			}
+			if src.isDraft() && !args.Drafts {
+				return nil
+			}
+			if !src.isPublished(now) {
+				return nil
+			}
+			if filepath.Dir(path) == args.InputDir && base == notFoundSource {
+				notFound = src
+				return nil
+			}
+		}
+
 		// in a non-root directory that has some renderable content, mark this
-		// directory as a subcategory of its parent
-		if dstDir := filepath.Dir(dst); dstDir != args.OutputDir && strings.HasSuffix(path, mdSuffix) {
-			key := filepath.Dir(dstDir)
-			res := dirsIndex[key]
-			dir := filepath.Base(filepath.Dir(dst))
-			// to avoid duplicates it's enough to check if the last element
-			// matches the one we're about to add because of the way
-			// directories are traversed
-			if len(res.categories) == 0 || res.categories[len(res.categories)-1].Dst != dir {
-				res.categories = append(res.categories, pageMeta{
-					Title: fileNameToTitle(dir),
-					Dst:   dir,
-				})
-				dirsIndex[key] = res
+		// directory and its parents as subcategories of their parents
+		for dstDir := filepath.Dir(dst); dstDir != args.OutputDir && src != nil; dstDir = filepath.Dir(dstDir) {
+			self := b.index(dstDir)
+			if self.listed {
+				break
 			}
+			self.listed = true
+			if self.meta, err = readDirMeta(filepath.Join(args.InputDir, strings.TrimPrefix(dstDir, args.OutputDir))); err != nil {
+				return err
+			}
+			category := pageMeta{
+				Title: fileNameToTitle(filepath.Base(dstDir)),
+				Dst:   filepath.Base(dstDir),
+				URL:   b.url(dstDir, true),
+				dst:   dstDir,
+			}
+			if m := self.meta; m != nil {
+				if m.Hidden {
+					continue
+				}
+				if m.Title != "" {
+					category.Title = m.Title
+				}
+				category.Description = m.Description
+				category.Summary = m.Description
+				category.weight = m.Weight
+			}
+			res := b.index(filepath.Dir(dstDir))
+			res.categories = append(res.categories, category)
 		}
 
 		key := filepath.Dir(dst)
-		if !strings.HasSuffix(path, mdSuffix) {
+		if src == nil {
+			if base == dirTemplateName || base == dirMetaName {
+				return nil
+			}
+			if filepath.Dir(path) == args.InputDir && slices.Contains(configNames, base) {
+				return nil
+			}
 			if base == "index.html" {
 				skipIndex[key] = struct{}{}
 			}
+			b.outputs[dst] = struct{}{}
 			return copyFile(dst, path)
 		}
 
@@ -205,35 +466,175 @@ func run(args runArgs) error {
 			base = strings.TrimSuffix(base, mdSuffix) + htmlSuffix
 			dst = strings.TrimSuffix(dst, mdSuffix) + htmlSuffix
 		}
-		title, err := renderFile(tpl, convert, mtime, args.SuffixHTML, dst, path)
-		if err != nil {
-			return err
-		}
-		res := dirsIndex[key]
-		res.pages = append(res.pages, pageMeta{Title: title, Dst: base, src: path})
-		dirsIndex[key] = res
+		res := b.index(key)
+		res.pages = append(res.pages, pageMeta{Dst: base, src: src, dst: dst})
 		return nil
 	}
 	if err := filepath.WalkDir(args.InputDir, walkFunc); err != nil {
-		return nil
+		return err
 	}
 
-	for dir, res := range dirsIndex {
+	// pages can only be rendered once titles of all pages and directories
+	// are known, so convert every page to HTML first
+	dirs := slices.Sorted(maps.Keys(b.dirsIndex))
+	for _, dir := range dirs {
+		res := b.dirsIndex[dir]
+		for i := range res.pages {
+			if err := b.convertPage(&res.pages[i]); err != nil {
+				return err
+			}
+		}
+		order := args.Sort
+		if res.meta != nil && res.meta.Sort != "" {
+			order = res.meta.Sort
+		}
+		sortPages(res.pages, order)
+		sortPages(res.categories, order)
+	}
+	b.site.Tags = b.collectTags()
+	b.navTree = b.tree(args.OutputDir)
+	for _, dir := range dirs {
+		for _, meta := range b.dirsIndex[dir].pages {
+			if err := b.renderFile(meta); err != nil {
+				return err
+			}
+		}
 		if _, ok := skipIndex[dir]; ok {
 			continue
 		}
-		if err := renderIndex(tpl, convert, dir, args.SuffixHTML, res.pages, res.categories); err != nil {
+		if err := b.renderIndex(dir); err != nil {
 			return err
 		}
 	}
+	if err := b.renderTags(); err != nil {
+		return err
+	}
+	if err := b.renderAliases(); err != nil {
+		return err
+	}
+	return b.renderNotFound(notFound)
+}
+
+// builder holds state shared by rendering of all pages and indexes.
+type builder struct {
+	args    runArgs
+	tpls    *templateSet
+	convert convertFunc
+	site    *Site
+	repo    *gitRepo // nil if source directory is not in a git work tree
+	// used to build index.html files. Key is a *destination* directory.
+	dirsIndex map[string]*dirIndex
+	navTree   *treeNode // whole site tree, see Site.Tree
+	// destination files written so far
+	outputs map[string]struct{}
+}
+
+// writeFile writes data to the destination file name, creating parent
+// directories as necessary, and records name as written.
+func (b *builder) writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	b.outputs[name] = struct{}{}
+	return os.WriteFile(name, data, 0666)
+}
+
+// siteFor returns site-wide values for rendering the page with destination
+// path dst, which may be a directory for index pages.
+func (b *builder) siteFor(dst string) *Site {
+	site := *b.site
+	site.Tree = b.navTree.mark(dst)
+	return &site
+}
+
+// dirIndex describes a destination directory
+type dirIndex struct {
+	pages      []pageMeta // pages in this directory
+	categories []pageMeta // subdirectories that contain .md files
+	meta       *dirMeta   // content of the directory metadata file, if any
+	listed     bool       // whether directory was already processed as a subcategory of its parent
+}
+
+// index returns index of the destination directory dir, creating it if
+// necessary.
+func (b *builder) index(dir string) *dirIndex {
+	res, ok := b.dirsIndex[dir]
+	if !ok {
+		res = &dirIndex{}
+		b.dirsIndex[dir] = res
+	}
+	return res
+}
+
+// readme returns the README page of a directory, or nil if it has none.
+func (res *dirIndex) readme(withLinkRewrite bool) *pageMeta {
+	readmeName := "README.md"
+	if withLinkRewrite {
+		readmeName = "README.html"
+	}
+	for i := range res.pages {
+		if res.pages[i].Dst == readmeName {
+			return &res.pages[i]
+		}
+	}
 	return nil
 }
 
+// siblings returns pages preceding and following page with destination path
+// dst in the directory, README page excluded. Either may be nil.
+func (res *dirIndex) siblings(dst string, withLinkRewrite bool) (prev, next *pageMeta) {
+	readme := res.readme(withLinkRewrite)
+	if readme != nil && readme.dst == dst {
+		return nil, nil
+	}
+	var found bool
+	for _, meta := range res.pages {
+		if readme != nil && meta.dst == readme.dst {
+			continue
+		}
+		if found {
+			return prev, &meta
+		}
+		if meta.dst == dst {
+			found = true
+			continue
+		}
+		prev = &meta
+	}
+	if !found {
+		return nil, nil
+	}
+	return prev, nil
+}
+
 // pageMeta is an element of a directory index
 type pageMeta struct {
-	Title string // page title
-	Dst   string // destination file name
-	src   string // source file name
+	Title       string    // page title
+	Dst         string    // destination file name
+	URL         string    // page URL relative to the site root, see relURL template function
+	Date        time.Time // "date" front matter value, if set
+	Description string    // "description" front matter value, or directory description
+	Tags        []string  // "tags" front matter value
+	Summary     string    // "description" front matter value, or text before <!--more--> line, or the first paragraph
+	WordCount   int       // number of words in page text, excluding code blocks
+	ReadingTime int       // estimated reading time in minutes
+
+	src     *mdSource     // source file, nil for directories
+	dst     string        // destination file or directory path
+	content template.HTML // page converted to HTML
+	heading string        // page title from its front matter or the first heading
+	toc     []*tocEntry   // page table of contents
+	weight  int           // "weight" front matter value, used for ordering
+	aliases []string      // "aliases" front matter value
+}
+
+// mtime returns modification time of the page source, or zero time for
+// directories.
+func (meta *pageMeta) mtime() time.Time {
+	if meta.src == nil {
+		return time.Time{}
+	}
+	return meta.src.mtime
 }
 
 // copyFile unlinks dst to ensure it does not exist, then tries to create hard
@@ -279,110 +680,366 @@ func copyFile(dst, src string) error {
 	return nil
 }
 
-// renderFile converts Markdown file src into HTML using convert function, then
-// renders it to dst file using template tpl. It returns title of rendered page
-// and error, if any. After writing to dst, function sets modification time of
-// dst either to mtime argument or modification time of src, whichever is most
-// recent.
-func renderFile(tpl *template.Template, convert convertFunc, mtime time.Time, withLinkRewrite bool, dst, src string) (string, error) {
-	if src == dst {
-		return "", errors.New("source and destination cannot be the same")
-	}
-	f, err := os.Open(src)
+// mdSource is a Markdown file split into its front matter and body.
+type mdSource struct {
+	path   string
+	params map[string]any // front matter values, nil if file has none
+	body   []byte         // Markdown text following the front matter
+	mtime  time.Time      // file modification time, or its last commit date
+	commit *gitCommit     // last git commit that changed the file, if known
+}
+
+// readSource reads Markdown file at path and parses its front matter.
+func readSource(path string) (*mdSource, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	src := &mdSource{path: path}
+	if fi, err := f.Stat(); err == nil {
+		src.mtime = fi.ModTime()
+	}
+	if src.params, src.body, err = splitFrontMatter(b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return src, nil
+}
+
+// isDraft reports whether source is a draft page: either its name has
+// ".draft.md" suffix, or it has "draft: true" in its front matter.
+func (src *mdSource) isDraft() bool {
+	if strings.HasSuffix(src.path, draftSuffix) {
+		return true
+	}
+	draft, _ := src.params["draft"].(bool)
+	return draft
+}
+
+// convertPage converts Markdown source of the page into HTML using convert
+// function, and fills page title and URL.
+func (b *builder) convertPage(meta *pageMeta) error {
+	if meta.src.path == meta.dst {
+		return errors.New("source and destination cannot be the same")
+	}
+	text, shortcodes, err := expandShortcodes(meta.src.body, b.tpls.shortcode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", meta.src.path, err)
+	}
+	summary, body := splitSummary(text)
 	out := new(bytes.Buffer)
-	if err := convert(out, f); err != nil {
-		return "", err
+	if err := b.convert(out, bytes.NewReader(body)); err != nil {
+		return err
 	}
-	if withLinkRewrite {
-		b, err := rewriteLinks(out.Bytes())
+	withAnchors, toc, err := createAnchors(replaceShortcodes(out.Bytes(), shortcodes), true)
+	if err != nil {
+		return fmt.Errorf("%s: create anchors on header elements: %w", meta.src.path, err)
+	}
+	out = bytes.NewBuffer(withAnchors)
+	meta.toc = toc
+	if b.args.SuffixHTML {
+		rewritten, err := rewriteLinks(out.Bytes())
 		if err != nil {
-			return "", err
+			return err
 		}
 		out.Reset()
-		out.Write(b)
+		out.Write(rewritten)
 		// TODO: consolidate this with the call to firstHeading below to reduce
 		// duplicate html parsing
 	}
-	title := fileNameToTitle(filepath.Base(dst))
-	if s, err := firstHeading(out.Bytes()); err == nil && s != "" {
-		title = s
+	meta.content = template.HTML(out.Bytes())
+	meta.heading = paramString(meta.src.params, "title")
+	if meta.heading == "" {
+		if s, err := firstHeading(out.Bytes()); err == nil && s != "" {
+			meta.heading = s
+		}
 	}
-	page := &Page{
-		Title:   title,
-		Content: template.HTML(out.Bytes()),
+	meta.Title = meta.heading
+	if meta.Title == "" {
+		meta.Title = fileNameToTitle(meta.Dst)
 	}
-	out.Reset()
-	if err := tpl.Execute(out, page); err != nil {
-		return "", err
+	meta.URL = b.url(meta.dst, false)
+	meta.Date = paramTime(meta.src.params, "date")
+	meta.Description = paramString(meta.src.params, "description")
+	meta.Tags = paramStrings(meta.src.params, "tags")
+	switch {
+	case meta.Description != "":
+		meta.Summary = meta.Description
+	case summary != nil:
+		out.Reset()
+		if err := b.convert(out, bytes.NewReader(summary)); err != nil {
+			return err
+		}
+		s, err := htmlText(replaceShortcodes(out.Bytes(), shortcodes))
+		if err != nil {
+			return err
+		}
+		meta.Summary = s
+	default:
+		s, err := firstParagraph([]byte(meta.content))
+		if err != nil {
+			return err
+		}
+		meta.Summary = s
 	}
-	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
-		return "", err
+	if meta.WordCount, err = wordCount([]byte(meta.content)); err != nil {
+		return err
 	}
-	if err := os.WriteFile(dst, out.Bytes(), 0666); err != nil {
-		return "", err
+	meta.ReadingTime = readingTime(meta.WordCount)
+	meta.aliases = paramStrings(meta.src.params, "aliases")
+	meta.weight = paramInt(meta.src.params, "weight")
+	return nil
+}
+
+// sourcePath returns slash-separated source path relative to the source
+// directory.
+func (b *builder) sourcePath(path string) string {
+	rel, err := filepath.Rel(b.args.InputDir, path)
+	if err != nil {
+		return ""
 	}
-	if fi, err := f.Stat(); err == nil {
-		if m := fi.ModTime(); m.After(mtime) {
-			mtime = m
+	return filepath.ToSlash(rel)
+}
+
+// editURL returns URL to edit the source file path made from the -editurl
+// pattern, replacing "{path}" with the file path relative to the git work
+// tree root (or to the source directory, if it's not in a work tree), and
+// "{branch}" with the -branch flag value or the current git branch. It returns
+// an empty string if pattern is not set.
+func (b *builder) editURL(path string) string {
+	if b.args.EditURL == "" {
+		return ""
+	}
+	p, branch := b.sourcePath(path), b.args.Branch
+	if b.repo != nil {
+		p = b.repo.prefix + p
+		if branch == "" {
+			branch = b.repo.branch
 		}
-		_ = os.Chtimes(dst, mtime, mtime)
 	}
-	return title, nil
+	return strings.NewReplacer(
+		"{branch}", escapePath(branch),
+		"{path}", escapePath(p),
+	).Replace(b.args.EditURL)
 }
 
-// renderIndex writes index.html file to directory dir. If an element of pages
-// describes "README.md" file, this file is rendered using convert function to
-// HTML format. This HTML, and every other element from pages is then used to
-// render template tpl.
-func renderIndex(tpl *template.Template, convert convertFunc, dir string, withLinkRewrite bool, pages, categories []pageMeta) error {
-	var readme template.HTML
-	out := new(bytes.Buffer)
-	nonReadmePages := make([]pageMeta, 0, len(pages))
-	for _, meta := range pages {
-		readmeName := "README.md"
-		if withLinkRewrite {
-			readmeName = "README.html"
-		}
-		if meta.Dst != readmeName || readme != "" {
-			nonReadmePages = append(nonReadmePages, meta)
-			continue
+// checkEditURL verifies that the -editurl pattern can be filled: if it has
+// "{branch}" placeholder, branch must be set with the -branch flag, or the
+// source directory must be in a git work tree with a branch checked out.
+func (b *builder) checkEditURL() error {
+	if !strings.Contains(b.args.EditURL, "{branch}") || b.args.Branch != "" {
+		return nil
+	}
+	if b.repo == nil {
+		return errors.New("edit URL pattern has {branch} placeholder, but source directory is not in a git work tree; set branch with -branch flag")
+	}
+	if b.repo.branch == "" || b.repo.branch == "HEAD" {
+		return errors.New("edit URL pattern has {branch} placeholder, but git HEAD is detached; set branch with -branch flag")
+	}
+	return nil
+}
+
+// escapePath escapes each element of slash-separated path p to be used in URL.
+func escapePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, s := range elems {
+		elems[i] = url.PathEscape(s)
+	}
+	return strings.Join(elems, "/")
+}
+
+// url returns URL of the destination path dst relative to the site root. If
+// dst is a directory, dir must be true.
+func (b *builder) url(dst string, dir bool) string {
+	s, _ := siteURL(b.args.BaseURL, b.sitePath(dst, dir), false)
+	return s
+}
+
+// sitePath returns slash-separated destination path dst relative to the
+// destination directory. If dst is a directory, dir must be true.
+func (b *builder) sitePath(dst string, dir bool) string {
+	rel, err := filepath.Rel(b.args.OutputDir, dst)
+	if err != nil {
+		return ""
+	}
+	p := filepath.ToSlash(rel)
+	switch {
+	case p == ".":
+		p = ""
+	case dir:
+		p += "/"
+	}
+	return p
+}
+
+// dirTitle returns title of the destination directory dir: the title from its
+// metadata file, or the title of its README page, or a title derived from the
+// directory name.
+func (b *builder) dirTitle(dir string) string {
+	if res, ok := b.dirsIndex[dir]; ok {
+		if res.meta != nil && res.meta.Title != "" {
+			return res.meta.Title
 		}
-		b, err := os.ReadFile(meta.src)
-		if err != nil {
-			return err
+		if readme := res.readme(b.args.SuffixHTML); readme != nil && readme.heading != "" {
+			return readme.heading
 		}
-		if err := convert(out, bytes.NewReader(b)); err != nil {
-			return err
+	}
+	if dir == b.args.OutputDir {
+		if b.site.Title != "" {
+			return b.site.Title
 		}
-		b = out.Bytes()
-		if withLinkRewrite {
-			if b, err = rewriteLinks(b); err != nil {
-				return err
-			}
+		return fileNameToTitle(filepath.Base(b.args.InputDir))
+	}
+	return fileNameToTitle(filepath.Base(dir))
+}
+
+// breadcrumbs returns links to the destination directory dir and all its
+// parents, starting from the site root.
+func (b *builder) breadcrumbs(dir string) []pageMeta {
+	var out []pageMeta
+	for {
+		out = append(out, pageMeta{Title: b.dirTitle(dir), URL: b.url(dir, true)})
+		if dir == b.args.OutputDir || !strings.HasPrefix(dir, b.args.OutputDir) {
+			break
 		}
-		readme = template.HTML(b)
+		dir = filepath.Dir(dir)
 	}
-	title := fmt.Sprintf("%s index", filepath.Base(dir))
-	if readme != "" {
-		if s, err := firstHeading([]byte(readme)); err == nil && s != "" {
-			title = s
+	slices.Reverse(out)
+	return out
+}
+
+// isPublished reports whether source is published at the time now: its
+// "publishDate" front matter value (or "date" if it's not set) is not in the
+// future, and its "expiryDate" value, if set, is in the future.
+func (src *mdSource) isPublished(now time.Time) bool {
+	publish := paramTime(src.params, "publishDate")
+	if publish.IsZero() {
+		publish = paramTime(src.params, "date")
+	}
+	if publish.After(now) {
+		return false
+	}
+	expiry := paramTime(src.params, "expiryDate")
+	return expiry.IsZero() || expiry.After(now)
+}
+
+// renderFile renders converted page to its destination file using template
+// from tpls (see templateSet.pick). After writing the file, function sets its
+// modification time either to modification time of template used, or
+// modification time of the page source, whichever is most recent.
+func (b *builder) renderFile(meta pageMeta) error {
+	src := meta.src
+	current := meta.dst
+	if readme := b.dirsIndex[filepath.Dir(meta.dst)].readme(b.args.SuffixHTML); readme != nil && readme.dst == meta.dst {
+		// README pages are represented by their directories in the tree
+		current = filepath.Dir(meta.dst)
+	}
+	page := &Page{
+		Title:       meta.Title,
+		Content:     meta.content,
+		Date:        paramTime(src.params, "date"),
+		Description: paramString(src.params, "description"),
+		Params:      src.params,
+		Site:        b.siteFor(current),
+		Breadcrumbs: b.breadcrumbs(filepath.Dir(meta.dst)),
+		TOC:         meta.toc,
+		Tags:        meta.Tags,
+		Summary:     meta.Summary,
+		WordCount:   meta.WordCount,
+		ReadingTime: meta.ReadingTime,
+		SourcePath:  b.sourcePath(src.path),
+		URL:         meta.URL,
+		ModTime:     src.mtime,
+		Commit:      src.commit,
+		EditURL:     b.editURL(src.path),
+	}
+	page.Prev, page.Next = b.dirsIndex[filepath.Dir(meta.dst)].siblings(meta.dst, b.args.SuffixHTML)
+	tpl, mtime, err := b.tpls.pick(filepath.Dir(src.path), src.params, pageTemplate)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src.path, err)
+	}
+	if src.mtime.After(mtime) {
+		mtime = src.mtime
+	}
+	page.LastMod = mtime
+	out := new(bytes.Buffer)
+	if err := tpl.Execute(out, page); err != nil {
+		return err
+	}
+	if err := b.writeFile(meta.dst, out.Bytes()); err != nil {
+		return err
+	}
+	_ = os.Chtimes(meta.dst, mtime, mtime)
+	return nil
+}
+
+// renderIndex writes index.html file to the destination directory dir. If
+// directory has a README page, its HTML content is used for the index page.
+// Index page is rendered with template from tpls (see templateSet.pick) picked
+// for the source directory and README front matter.
+func (b *builder) renderIndex(dir string) error {
+	res := b.dirsIndex[dir]
+	var readme template.HTML
+	var params map[string]any
+	var toc []*tocEntry
+	var words int
+	nonReadmePages := make([]pageMeta, 0, len(res.pages))
+	readmeMeta := res.readme(b.args.SuffixHTML)
+	for _, meta := range res.pages {
+		if readmeMeta != nil && meta.dst == readmeMeta.dst {
+			readme, params, toc, words = meta.content, meta.src.params, meta.toc, meta.WordCount
+			continue
 		}
+		nonReadmePages = append(nonReadmePages, meta)
+	}
+	title := fmt.Sprintf("%s index", filepath.Base(dir))
+	if readmeMeta != nil && readmeMeta.heading != "" {
+		title = readmeMeta.heading
 	}
 	page := &Page{
-		Title:      title,
-		Content:    readme,
-		Pages:      nonReadmePages,
-		Categories: categories,
+		Title:       title,
+		Content:     readme,
+		TOC:         toc,
+		WordCount:   words,
+		ReadingTime: readingTime(words),
+		Date:        paramTime(params, "date"),
+		Description: paramString(params, "description"),
+		Params:      params,
+		Site:        b.siteFor(dir),
+		Pages:       nonReadmePages,
+		Categories:  res.categories,
+		URL:         b.url(dir, true),
+	}
+	if dir != b.args.OutputDir {
+		page.Breadcrumbs = b.breadcrumbs(filepath.Dir(dir))
 	}
-	out.Reset()
+	rel, err := filepath.Rel(b.args.OutputDir, dir)
+	if err != nil {
+		return err
+	}
+	tpl, mtime, err := b.tpls.pick(filepath.Join(b.args.InputDir, rel), params, indexTemplate)
+	if err != nil {
+		return fmt.Errorf("index of %s: %w", dir, err)
+	}
+	if readmeMeta != nil {
+		page.SourcePath = b.sourcePath(readmeMeta.src.path)
+		page.ModTime = readmeMeta.src.mtime
+		page.Commit = readmeMeta.src.commit
+		page.EditURL = b.editURL(readmeMeta.src.path)
+		if page.ModTime.After(mtime) {
+			mtime = page.ModTime
+		}
+	}
+	page.LastMod = mtime
+	out := new(bytes.Buffer)
 	if err := tpl.Execute(out, page); err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(dir, "index.html"), out.Bytes(), 0666)
+	return b.writeFile(filepath.Join(dir, "index.html"), out.Bytes())
 }
 
 // serve runs HTTP server listening on addr that serves static files from dir
@@ -396,30 +1053,65 @@ func serve(addr, dir string) error {
 		return err
 	}
 	defer ln.Close()
-	fileServer := http.FileServer(http.Dir(dir))
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
-			w.Header().Set("Strict-Transport-Security", "max-age=31536000; preload")
-		}
-		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
-		w.Header().Set("Referrer-Policy", "same-origin")
-		fileServer.ServeHTTP(w, r)
-	})
 	log.Printf("serving on http://%s/", ln.Addr())
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      handler,
+		Handler:      siteHandler(dir),
 		ReadTimeout:  time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 	return srv.Serve(ln)
 }
 
+// siteHandler returns handler serving static files from dir. Requests for
+// missing files get "404.html" page from dir, if it exists.
+func siteHandler(dir string) http.Handler {
+	root := http.Dir(dir)
+	fileServer := http.FileServer(root)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+			w.Header().Set("Strict-Transport-Security", "max-age=31536000; preload")
+		}
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		if f, err := root.Open(r.URL.Path); err == nil {
+			f.Close()
+		} else if errors.Is(err, fs.ErrNotExist) {
+			// serve rendered "page not found" page, if there's one
+			if b, err := os.ReadFile(filepath.Join(dir, notFoundPage)); err == nil {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(b)
+				return
+			}
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
 type Page struct {
-	Title      string
-	Content    template.HTML
-	Pages      []pageMeta // non-empty only for index pages
-	Categories []pageMeta // non-empty only for index pages
+	Title       string
+	Content     template.HTML
+	Date        time.Time      // "date" front matter value, if set
+	Description string         // "description" front matter value, if set
+	Tags        []string       // "tags" front matter value
+	Summary     string         // short page summary, see pageMeta
+	WordCount   int            // number of words in page text, excluding code blocks
+	ReadingTime int            // estimated reading time in minutes
+	SourcePath  string         // slash-separated source file path relative to the source directory
+	URL         string         // page URL relative to the site root
+	ModTime     time.Time      // source file modification time, or its last commit date; the latest of tagged pages for tag pages
+	LastMod     time.Time      // latest of source file and template modification times
+	Commit      *gitCommit     // last git commit that changed the source file, nil if unknown
+	EditURL     string         // URL to edit the source file, empty unless -editurl flag is set
+	Params      map[string]any // all front matter values
+	Site        *Site          // site-wide values
+	Breadcrumbs []pageMeta     // links to parent directories, starting from the site root
+	Prev        *pageMeta      // previous page in the same directory, nil for index pages
+	Next        *pageMeta      // next page in the same directory, nil for index pages
+	TOC         []*tocEntry    // table of contents built from page headings
+	Pages       []pageMeta     // non-empty only for index pages
+	Categories  []pageMeta     // non-empty only for index pages
 }
 
 // convertFunc converts Markdown source src to HTML and writes it to dst. HTML
@@ -447,43 +1139,26 @@ func cmarkConvert(dst io.Writer, src io.Reader) error {
 	if err != nil {
 		return err
 	}
-	if b, err = createAnchors(b, true); err != nil {
-		return fmt.Errorf("create anchors on header elements: %w", err)
-	}
 	_, err = dst.Write(b)
 	return err
 }
 
-// latestMtime stats each file matching pattern pat and returns the latest
-// mtime of them all.
-func latestMtime(fsys fs.FS, pat string) (time.Time, error) {
-	names, err := fs.Glob(fsys, pat)
-	if err != nil {
-		return time.Time{}, err
-	}
-	var mtime time.Time
-	for _, name := range names {
-		fi, err := fs.Stat(fsys, name)
-		if err != nil {
-			return time.Time{}, err
-		}
-		if m := fi.ModTime(); m.After(mtime) {
-			mtime = m
-		}
-	}
-	return mtime, nil
-}
-
 func fileNameToTitle(name string) string {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, mdSuffix), draftMark)
+	if s := numPrefix.ReplaceAllString(name, ""); s != "" {
+		name = s
+	}
 	if strings.ContainsAny(name, " ") {
-		return strings.TrimSuffix(name, mdSuffix)
+		return name
 	}
-	return repl.Replace(strings.TrimSuffix(name, mdSuffix))
+	return repl.Replace(name)
 }
 
 var repl = strings.NewReplacer("-", " ")
 
 const mdSuffix = ".md"
+const draftMark = ".draft"
+const draftSuffix = draftMark + mdSuffix
 const htmlSuffix = ".html"
 const gfmBinary = "cmark-gfm" // https://github.com/github/cmark-gfm binary
 
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_builder_breadcrumbs(t *testing.T) {
+	out := filepath.FromSlash("/site/output")
+	b := &builder{
+		args: runArgs{InputDir: filepath.FromSlash("/site/src"), OutputDir: out, BaseURL: "/docs/"},
+		site: &Site{Title: "Docs"},
+		dirsIndex: map[string]*dirIndex{
+			filepath.Join(out, "api"): {pages: []pageMeta{{Dst: "README.md", heading: "API Reference"}}},
+		},
+	}
+	got := b.breadcrumbs(filepath.Join(out, "api", "v2-beta"))
+	want := []pageMeta{
+		{Title: "Docs", URL: "/docs/"},
+		{Title: "API Reference", URL: "/docs/api/"},
+		{Title: "v2 beta", URL: "/docs/api/v2-beta/"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func Test_dirIndex_siblings(t *testing.T) {
+	res := &dirIndex{pages: []pageMeta{
+		{Dst: "01-intro.md", dst: "01-intro.md"},
+		{Dst: "README.md", dst: "README.md"},
+		{Dst: "02-setup.md", dst: "02-setup.md"},
+		{Dst: "03-usage.md", dst: "03-usage.md"},
+	}}
+	for _, tc := range []struct {
+		dst, prev, next string
+	}{
+		{"01-intro.md", "", "02-setup.md"},
+		{"02-setup.md", "01-intro.md", "03-usage.md"},
+		{"03-usage.md", "02-setup.md", ""},
+		{"README.md", "", ""},
+	} {
+		prev, next := res.siblings(tc.dst, false)
+		var gotPrev, gotNext string
+		if prev != nil {
+			gotPrev = prev.Dst
+		}
+		if next != nil {
+			gotNext = next.Dst
+		}
+		if gotPrev != tc.prev || gotNext != tc.next {
+			t.Errorf("%s: got %q/%q, want %q/%q", tc.dst, gotPrev, gotNext, tc.prev, tc.next)
+		}
+	}
+}
+
+func Test_mdSource_isPublished(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		params map[string]any
+		want   bool
+	}{
+		{nil, true},
+		{map[string]any{"date": "2024-04-30"}, true},
+		{map[string]any{"date": "2024-05-02"}, false},
+		{map[string]any{"date": "2024-04-30", "publishDate": "2024-05-02"}, false},
+		{map[string]any{"date": "2024-05-02", "publishDate": "2024-04-30"}, true},
+		{map[string]any{"expiryDate": "2024-05-01T12:00:00Z"}, false},
+		{map[string]any{"expiryDate": "2024-05-02"}, true},
+	} {
+		src := &mdSource{params: tc.params}
+		if got := src.isPublished(now); got != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.params, got, tc.want)
+		}
+	}
+}
+
+func Test_mdSource_isDraft(t *testing.T) {
+	for _, tc := range []struct {
+		path   string
+		params map[string]any
+		want   bool
+	}{
+		{"docs/page.draft.md", nil, true},
+		{"docs/page.md", map[string]any{"draft": true}, true},
+		{"docs/page.md", map[string]any{"draft": false}, false},
+		{"docs/page.md", map[string]any{"draft": "yes"}, false},
+		{"docs/page.md", nil, false},
+	} {
+		src := &mdSource{path: tc.path, params: tc.params}
+		if got := src.isDraft(); got != tc.want {
+			t.Errorf("%s %v: got %v, want %v", tc.path, tc.params, got, tc.want)
+		}
+	}
+}
+
+func Test_run_drafts(t *testing.T) {
+	dir := t.TempDir()
+	src, tpls := filepath.Join(dir, "src"), filepath.Join(dir, "templates")
+	for name, text := range map[string]string{
+		filepath.Join(src, "page.md"):              "# Page\n",
+		filepath.Join(src, "drafts", "a.draft.md"): "# Draft\n",
+		filepath.Join(src, "hidden", "b.md"):       "---\ndraft: true\n---\n# Hidden\n",
+		filepath.Join(tpls, "default.html"):        "page",
+		filepath.Join(tpls, indexTemplate):         "{{range .Categories}}{{.Title}},{{end}}:{{range .Pages}}{{.Title}},{{end}}",
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "output")
+	if err := run(runArgs{InputDir: src, OutputDir: out, TemplatesDir: tpls, Sort: "name"}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), ":Page,"; got != want {
+		t.Fatalf("got index %q, want %q", got, want)
+	}
+	for _, name := range []string{"drafts", "hidden"} {
+		if _, err := os.Stat(filepath.Join(out, name)); !os.IsNotExist(err) {
+			t.Errorf("%s: draft-only directory was rendered (err: %v)", name, err)
+		}
+	}
+}
+
+func Test_builder_editURL(t *testing.T) {
+	src := filepath.FromSlash("/site/src")
+	b := &builder{
+		args: runArgs{InputDir: src, EditURL: "https://example.com/repo/edit/{branch}/{path}"},
+		repo: &gitRepo{prefix: "docs/", branch: "main"},
+	}
+	path := filepath.Join(src, "api", "new page.md")
+	if got, want := b.editURL(path), "https://example.com/repo/edit/main/docs/api/new%20page.md"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	b.args.Branch = "release/v2"
+	b.repo = nil
+	if got, want := b.editURL(path), "https://example.com/repo/edit/release/v2/api/new%20page.md"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, tc := range []struct {
+		branch  string
+		repo    *gitRepo
+		wantErr bool
+	}{
+		{"", &gitRepo{branch: "main"}, false},
+		{"main", nil, false},
+		{"main", &gitRepo{branch: "HEAD"}, false},
+		{"", nil, true},
+		{"", &gitRepo{branch: "HEAD"}, true},
+	} {
+		b.args.Branch, b.repo = tc.branch, tc.repo
+		if err := b.checkEditURL(); (err != nil) != tc.wantErr {
+			t.Errorf("branch %q, repo %+v: got error %v, want error: %v", tc.branch, tc.repo, err, tc.wantErr)
+		}
+	}
+	b.args.Branch, b.repo = "", nil
+	b.args.EditURL = "https://example.com/repo/edit/main/{path}"
+	if err := b.checkEditURL(); err != nil {
+		t.Errorf("pattern without {branch}: %v", err)
+	}
+}
+
+func Test_run_rootDirMeta(t *testing.T) {
+	dir := t.TempDir()
+	src, tpls := filepath.Join(dir, "src"), filepath.Join(dir, "templates")
+	for name, text := range map[string]string{
+		filepath.Join(src, "alpha.md"):      "# Alpha\n",
+		filepath.Join(src, "zed.md"):        "# Zed\n",
+		filepath.Join(src, dirMetaName):     "title: Root\nsort: title,reverse\n",
+		filepath.Join(tpls, "default.html"): "page",
+		filepath.Join(tpls, indexTemplate):  "{{.Site.Tree.Title}}:{{range .Pages}}{{.Title}},{{end}}",
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "output")
+	if err := run(runArgs{InputDir: src, OutputDir: out, TemplatesDir: tpls, Sort: "name"}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "Root:Zed,Alpha,"; got != want {
+		t.Fatalf("got index %q, want %q", got, want)
+	}
+}
+
+func Test_run_pageMeta(t *testing.T) {
+	dir := t.TempDir()
+	src, tpls := filepath.Join(dir, "src"), filepath.Join(dir, "templates")
+	const meta = "{{.SourcePath}}|{{.URL}}|{{.ModTime.Format \"2006\"}}|{{.LastMod.Format \"2006\"}}"
+	srcTime := map[string]time.Time{
+		filepath.Join(src, "page.md"):          time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		filepath.Join(src, "sub", "README.md"): time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for name, text := range map[string]string{
+		filepath.Join(src, "page.md"):          "# Page\n",
+		filepath.Join(src, "sub", "README.md"): "# Sub\n",
+		filepath.Join(tpls, "default.html"):    meta,
+		filepath.Join(tpls, indexTemplate):     meta,
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+		mtime, ok := srcTime[name]
+		if !ok {
+			mtime = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC) // templates
+		}
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "output")
+	if err := run(runArgs{InputDir: src, OutputDir: out, TemplatesDir: tpls, Sort: "name"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"page.md":                          "page.md|/page.md|2022|2022",
+		filepath.Join("sub", "index.html"): "sub/README.md|/sub/|2020|2021",
+	} {
+		b, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+)
+
+// renderNotFound writes the "404.html" file to the root of the destination
+// directory. Page content comes from src, which is the "404.md" source file,
+// if it exists; such page is rendered with "404.html" template, unless it
+// picks one in its front matter, falling back to the page template (see
+// templateSet.pick). Without the source file the page is only written if
+// "404.html" template exists.
+func (b *builder) renderNotFound(src *mdSource) error {
+	if src == nil && b.tpls.lookup(notFoundTemplate) == nil {
+		return nil
+	}
+	dst := filepath.Join(b.args.OutputDir, notFoundPage)
+	if _, ok := b.outputs[dst]; ok {
+		return fmt.Errorf("cannot create %s: file already exists", notFoundPage)
+	}
+	page := &Page{
+		Title: notFoundTitle,
+		URL:   b.url(dst, false),
+		Site:  b.siteFor(dst),
+	}
+	if src != nil {
+		meta := &pageMeta{Dst: notFoundPage, src: src, dst: dst}
+		if err := b.convertPage(meta); err != nil {
+			return err
+		}
+		if meta.heading != "" {
+			page.Title = meta.heading
+		}
+		page.Content = meta.content
+		page.Description = meta.Description
+		page.Params = src.params
+		page.SourcePath = b.sourcePath(src.path)
+		page.ModTime = src.mtime
+		page.Commit = src.commit
+		page.EditURL = b.editURL(src.path)
+	}
+	var tpl *template.Template
+	mtime := b.tpls.mtime
+	if paramString(page.Params, "template") == "" {
+		tpl = b.tpls.lookup(notFoundTemplate)
+	}
+	if tpl == nil {
+		var err error
+		if tpl, mtime, err = b.tpls.pick(b.args.InputDir, page.Params, pageTemplate); err != nil {
+			return fmt.Errorf("%s: %w", src.path, err)
+		}
+	}
+	if src != nil && src.mtime.After(mtime) {
+		mtime = src.mtime
+	}
+	page.LastMod = mtime
+	out := new(bytes.Buffer)
+	if err := tpl.Execute(out, page); err != nil {
+		return err
+	}
+	return b.writeFile(dst, out.Bytes())
+}
+
+const (
+	notFoundPage     = "404.html" // destination file name of the "page not found" page
+	notFoundSource   = "404.md"   // source of the "page not found" page
+	notFoundTemplate = "404.html" // template used to render "page not found" page
+	notFoundTitle    = "Page not found"
+)
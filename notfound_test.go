@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_siteHandler(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte("page"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	h := siteHandler(dir)
+	check := func(path string, wantCode int, wantBody string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != wantCode {
+			t.Errorf("%s: got status %d, want %d", path, rec.Code, wantCode)
+		}
+		if wantBody != "" && rec.Body.String() != wantBody {
+			t.Errorf("%s: got body %q, want %q", path, rec.Body, wantBody)
+		}
+	}
+	check("/page.html", http.StatusOK, "page")
+	check("/missing.html", http.StatusNotFound, "")
+	if err := os.WriteFile(filepath.Join(dir, notFoundPage), []byte("not found"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	check("/missing.html", http.StatusNotFound, "not found")
+	check("/page.html", http.StatusOK, "page")
+}
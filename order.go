@@ -0,0 +1,85 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sortKeys are valid keys for the -sort flag
+var sortKeys = []string{"name", "title", "date", "mtime"}
+
+// parseSortOrder parses value of the -sort flag: one of sortKeys, optionally
+// followed by ",reverse".
+func parseSortOrder(s string) (key string, reverse bool, err error) {
+	key, mod, _ := strings.Cut(s, ",")
+	if !slices.Contains(sortKeys, key) {
+		return "", false, fmt.Errorf("unsupported sort key %q, valid keys are: %s", key, strings.Join(sortKeys, ", "))
+	}
+	switch mod {
+	case "":
+	case "reverse":
+		reverse = true
+	default:
+		return "", false, fmt.Errorf("unsupported sort modifier %q", mod)
+	}
+	return key, reverse, nil
+}
+
+// sortPages sorts pages in place. Pages with non-zero weight come first, in
+// the order of increasing weight. Other pages are ordered by key (see
+// sortKeys), in reverse if reverse is true. Ties are resolved by comparing
+// names. Order must be valid, see parseSortOrder.
+func sortPages(pages []pageMeta, order string) {
+	key, reverse, _ := parseSortOrder(order)
+	slices.SortStableFunc(pages, func(a, b pageMeta) int {
+		switch {
+		case a.weight != 0 && b.weight != 0:
+			if c := cmp.Compare(a.weight, b.weight); c != 0 {
+				return c
+			}
+		case a.weight != 0:
+			return -1
+		case b.weight != 0:
+			return 1
+		}
+		var c int
+		switch key {
+		case "title":
+			c = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		case "date":
+			c = a.Date.Compare(b.Date)
+		case "mtime":
+			c = a.mtime().Compare(b.mtime())
+		}
+		if c == 0 {
+			c = compareNames(a.Dst, b.Dst)
+		}
+		if reverse {
+			return -c
+		}
+		return c
+	})
+}
+
+// compareNames compares file names, if both have numeric prefixes, such as
+// "2-setup.md" and "10-usage.md", these prefixes are compared as numbers.
+func compareNames(a, b string) int {
+	if m1, m2 := numPrefix.FindString(a), numPrefix.FindString(b); m1 != "" && m2 != "" {
+		n1, err1 := strconv.Atoi(strings.TrimRight(m1, numPrefixSeparators))
+		n2, err2 := strconv.Atoi(strings.TrimRight(m2, numPrefixSeparators))
+		if err1 == nil && err2 == nil && n1 != n2 {
+			return cmp.Compare(n1, n2)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// numPrefix matches numeric ordering prefix of file names, as in
+// "01-intro.md"
+var numPrefix = regexp.MustCompile(`^[0-9]+[-_ ]+`)
+
+const numPrefixSeparators = "-_ "
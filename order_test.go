@@ -0,0 +1,49 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_sortPages(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	pages := []pageMeta{
+		{Dst: "10-usage.md", Title: "Usage", Date: day(3)},
+		{Dst: "2-setup.md", Title: "Setup", Date: day(1)},
+		{Dst: "faq.md", Title: "FAQ", Date: day(2)},
+		{Dst: "start.md", Title: "Getting started", weight: 1},
+	}
+	for _, tc := range []struct {
+		order string
+		want  []string
+	}{
+		{"name", []string{"start.md", "2-setup.md", "10-usage.md", "faq.md"}},
+		{"title", []string{"start.md", "faq.md", "2-setup.md", "10-usage.md"}},
+		{"date,reverse", []string{"start.md", "10-usage.md", "faq.md", "2-setup.md"}},
+	} {
+		sortPages(pages, tc.order)
+		var got []string
+		for _, p := range pages {
+			got = append(got, p.Dst)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.order, got, tc.want)
+		}
+	}
+	if _, _, err := parseSortOrder("size"); err == nil {
+		t.Error("invalid sort key should be rejected")
+	}
+}
+
+func Test_fileNameToTitle(t *testing.T) {
+	for name, want := range map[string]string{
+		"01-getting-started.md": "getting started",
+		"2024.md":               "2024",
+		"my notes.draft.md":     "my notes",
+	} {
+		if got := fileNameToTitle(name); got != want {
+			t.Errorf("fileNameToTitle(%q): got %q, want %q", name, got, want)
+		}
+	}
+}
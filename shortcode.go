@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// shortcode is a template invocation from Markdown source, written as
+// {{< name arg key="value" >}} for standalone shortcodes, or as
+// {{< name >}}inner text{{< /name >}} for shortcodes with content. It is
+// rendered with template "name.html" from the "shortcodes" subdirectory of
+// the templates directory.
+type shortcode struct {
+	Name   string
+	Args   []string          // positional arguments
+	Params map[string]string // named arguments
+	Inner  string            // raw text between opening and closing tags
+}
+
+// Get returns positional argument if key is an int, or named argument
+// otherwise. It returns an empty string if there's no such argument.
+func (sc *shortcode) Get(key any) string {
+	if i, ok := key.(int); ok {
+		if i >= 0 && i < len(sc.Args) {
+			return sc.Args[i]
+		}
+		return ""
+	}
+	return sc.Params[fmt.Sprint(key)]
+}
+
+// expandShortcodes finds shortcodes in Markdown text src and renders them with
+// render function. It returns src with each shortcode replaced by a
+// placeholder, and rendered HTML for each placeholder, to be put back with
+// replaceShortcodes once the text is converted to HTML. Shortcodes escaped as
+// {{</* name */>}} are replaced with their unescaped text.
+func expandShortcodes(src []byte, render func(*shortcode) (string, error)) ([]byte, []string, error) {
+	if !bytes.Contains(src, shortcodeOpen) {
+		return src, nil, nil
+	}
+	var out bytes.Buffer
+	var outputs []string
+	for {
+		i := bytes.Index(src, shortcodeOpen)
+		if i < 0 {
+			out.Write(src)
+			return out.Bytes(), outputs, nil
+		}
+		out.Write(src[:i])
+		src = src[i:]
+		if rest := bytes.TrimLeft(src[len(shortcodeOpen):], " "); bytes.HasPrefix(rest, []byte("/*")) {
+			end := bytes.Index(rest, []byte("*/"+string(shortcodeClose)))
+			if end < 0 {
+				return nil, nil, fmt.Errorf("unterminated escaped shortcode")
+			}
+			out.Write(shortcodeOpen)
+			out.Write(rest[len("/*"):end])
+			out.Write(shortcodeClose)
+			src = rest[end+len("*/")+len(shortcodeClose):]
+			continue
+		}
+		tag, n, err := scanShortcodeTag(src)
+		if err != nil {
+			return nil, nil, err
+		}
+		src = src[n:]
+		name, args := strings.TrimSpace(tag), ""
+		if i := strings.IndexAny(name, shortcodeSpace); i >= 0 {
+			name, args = name[:i], name[i:]
+		}
+		if name == "" {
+			return nil, nil, fmt.Errorf("shortcode without a name")
+		}
+		if strings.HasPrefix(name, "/") {
+			return nil, nil, fmt.Errorf("closing shortcode %q without opening one", name)
+		}
+		sc := &shortcode{Name: name}
+		if sc.Args, sc.Params, err = parseShortcodeArgs(args); err != nil {
+			return nil, nil, fmt.Errorf("shortcode %q: %w", name, err)
+		}
+		closing := regexp.MustCompile(`\{\{<\s*/` + regexp.QuoteMeta(name) + `\s*>\}\}`)
+		if loc := closing.FindIndex(src); loc != nil {
+			sc.Inner = string(src[:loc[0]])
+			src = src[loc[1]:]
+		}
+		s, err := render(sc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("shortcode %q: %w", name, err)
+		}
+		out.WriteString(shortcodePlaceholder(len(outputs)))
+		outputs = append(outputs, s)
+	}
+}
+
+// scanShortcodeTag returns text of the shortcode tag at the beginning of src,
+// without delimiters, and the length of the tag including delimiters.
+func scanShortcodeTag(src []byte) (string, int, error) {
+	for i := len(shortcodeOpen); i < len(src); {
+		switch {
+		case bytes.HasPrefix(src[i:], shortcodeClose):
+			return string(src[len(shortcodeOpen):i]), i + len(shortcodeClose), nil
+		case src[i] == '"' || src[i] == '`':
+			q, err := strconv.QuotedPrefix(string(src[i:]))
+			if err != nil {
+				return "", 0, fmt.Errorf("shortcode has invalid quoted string")
+			}
+			i += len(q)
+		default:
+			i++
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated shortcode")
+}
+
+// parseShortcodeArgs parses space-separated shortcode arguments: either
+// positional, or named, as in key=value. Values with spaces must be quoted
+// with double quotes or backticks.
+func parseShortcodeArgs(s string) (args []string, params map[string]string, err error) {
+	for {
+		s = strings.TrimLeft(s, shortcodeSpace)
+		if s == "" {
+			return args, params, nil
+		}
+		var key string
+		if i := strings.IndexAny(s, "="+shortcodeSpace+"\"`"); i > 0 && s[i] == '=' {
+			key, s = s[:i], s[i+1:]
+		}
+		var val string
+		if s != "" && (s[0] == '"' || s[0] == '`') {
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid quoted string: %s", s)
+			}
+			val, _ = strconv.Unquote(q)
+			s = s[len(q):]
+		} else {
+			i := strings.IndexAny(s, shortcodeSpace)
+			if i < 0 {
+				i = len(s)
+			}
+			val, s = s[:i], s[i:]
+		}
+		if key == "" {
+			args = append(args, val)
+			continue
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[key] = val
+	}
+}
+
+// replaceShortcodes replaces placeholders left by expandShortcodes in HTML
+// text b with rendered shortcodes. Placeholders that were turned into
+// standalone paragraphs are replaced with paragraph tags as well.
+func replaceShortcodes(b []byte, outputs []string) []byte {
+	for i := range outputs {
+		ph := shortcodePlaceholder(i)
+		b = bytes.ReplaceAll(b, []byte("<p>"+ph+"</p>"), []byte(outputs[i]))
+		b = bytes.ReplaceAll(b, []byte(ph), []byte(outputs[i]))
+	}
+	return b
+}
+
+// shortcodePlaceholder returns text that replaces i-th shortcode in the
+// Markdown text; it is made to pass Markdown conversion unchanged.
+func shortcodePlaceholder(i int) string { return fmt.Sprintf("NOTHUGOSHORTCODE%dEND", i) }
+
+var (
+	shortcodeOpen  = []byte("{{<")
+	shortcodeClose = []byte(">}}")
+)
+
+const (
+	shortcodeSpace = " \t\r\n"
+	shortcodesDir  = "shortcodes" // templates subdirectory with shortcode templates
+)
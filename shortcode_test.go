@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_expandShortcodes(t *testing.T) {
+	const src = "Intro {{< video id=42 >}}\n\n" +
+		"{{< note \"Be careful\" kind=warning >}}\nInner *text*\n{{< /note >}}\n\n" +
+		"`{{</* video id=1 */>}}`\n"
+	var got []*shortcode
+	render := func(sc *shortcode) (string, error) {
+		got = append(got, sc)
+		return fmt.Sprintf("<%s>", sc.Name), nil
+	}
+	text, outputs, err := expandShortcodes([]byte(src), render)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*shortcode{
+		{Name: "video", Params: map[string]string{"id": "42"}},
+		{Name: "note", Args: []string{"Be careful"}, Params: map[string]string{"kind": "warning"}, Inner: "\nInner *text*\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got shortcodes:\n%+v\nwant:\n%+v", got, want)
+	}
+	wantText := "Intro " + shortcodePlaceholder(0) + "\n\n" + shortcodePlaceholder(1) + "\n\n`{{< video id=1 >}}`\n"
+	if string(text) != wantText {
+		t.Fatalf("got text:\n%q\nwant:\n%q", text, wantText)
+	}
+	html := "<p>Intro " + shortcodePlaceholder(0) + "</p>\n<p>" + shortcodePlaceholder(1) + "</p>\n"
+	if got, want := string(replaceShortcodes([]byte(html), outputs)), "<p>Intro <video></p>\n<note>\n"; got != want {
+		t.Fatalf("got HTML %q, want %q", got, want)
+	}
+	for _, src := range []string{"{{< video", "{{< /note >}}", "{{< x a=\"b >}}"} {
+		if _, _, err := expandShortcodes([]byte(src), render); err == nil {
+			t.Errorf("%q: expected error", src)
+		} else if !strings.Contains(err.Error(), "shortcode") {
+			t.Errorf("%q: unexpected error: %v", src, err)
+		}
+	}
+}
+
+func Test_shortcode_Get(t *testing.T) {
+	sc := &shortcode{Args: []string{"a"}, Params: map[string]string{"k": "v"}}
+	for key, want := range map[any]string{0: "a", 1: "", "k": "v", "x": ""} {
+		if got := sc.Get(key); got != want {
+			t.Errorf("Get(%v) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// wordCount parses b as utf-8 encoded HTML text and returns number of words
+// in its text content, not counting text inside <pre> elements.
+func wordCount(b []byte) (int, error) {
+	z := html.NewTokenizer(bytes.NewReader(b))
+	var words int
+	var depth int // level of <pre> elements nesting
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return words, nil
+			}
+			return 0, z.Err()
+		case html.TextToken:
+			if depth == 0 {
+				words += len(strings.Fields(string(z.Text())))
+			}
+		case html.StartTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			if atom.Lookup(name) != atom.Pre {
+				continue
+			}
+			if tt == html.StartTagToken {
+				depth++
+			} else if depth > 0 {
+				depth--
+			}
+		}
+	}
+}
+
+// readingTime returns estimated time in minutes to read the given number of
+// words, rounded up. It returns zero only if words is zero.
+func readingTime(words int) int {
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+const wordsPerMinute = 200
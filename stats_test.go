@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func Test_wordCount(t *testing.T) {
+	const src = "<h1>Some title</h1>\n<p>One <em>two</em>\nthree.</p>\n" +
+		"<pre><code>not counted\n</code></pre>\n<p>Four</p>"
+	got, err := wordCount([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 6; got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+}
+
+func Test_readingTime(t *testing.T) {
+	for words, want := range map[int]int{0: 0, 1: 1, 200: 1, 201: 2, 1000: 5} {
+		if got := readingTime(words); got != want {
+			t.Errorf("readingTime(%d) = %d, want %d", words, got, want)
+		}
+	}
+}
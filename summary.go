@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// splitSummary splits Markdown text b at the first summary marker line: a
+// line with only the marker, outside of fenced code blocks. If b has no such
+// line, it returns nil summary and b as is. Otherwise, summary is the text
+// before the marker line, and body is b with the marker line removed.
+func splitSummary(b []byte) (summary, body []byte) {
+	var fence []byte // opening code fence, if inside fenced code block
+	for off := 0; off < len(b); {
+		line := b[off:]
+		next := len(b)
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, next = line[:i], off+i+1
+		}
+		line = bytes.TrimRight(line, " \t\r")
+		if indented := bytes.TrimLeft(line, " "); len(line)-len(indented) <= 3 {
+			switch {
+			case fence != nil:
+				if bytes.HasPrefix(indented, fence) && strings.Trim(string(indented), string(fence[:1])) == "" {
+					fence = nil
+				}
+			case bytes.HasPrefix(indented, []byte("```")) || bytes.HasPrefix(indented, []byte("~~~")):
+				n := len(indented) - len(strings.TrimLeft(string(indented), string(indented[:1])))
+				fence = indented[:n]
+			case bytes.Equal(indented, summaryMarker):
+				summary = b[:off:off]
+				body = append(summary, b[next:]...)
+				return summary, body
+			}
+		}
+		off = next
+	}
+	return nil, b
+}
+
+var summaryMarker = []byte("<!--more-->")
+
+// firstParagraph partially parses b as utf-8 encoded HTML text and returns
+// text of the first <p> element it finds, with whitespace collapsed. If no
+// such element is found, but HTML was parsed successfully, result would be an
+// empty string and nil error.
+func firstParagraph(b []byte) (string, error) {
+	z := html.NewTokenizer(bytes.NewReader(b))
+	var textBuilder strings.Builder
+	var depth int // level of <p> elements nesting
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return collapseSpaces(textBuilder.String()), nil
+			}
+			return "", z.Err()
+		case html.TextToken:
+			if depth > 0 {
+				textBuilder.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			if atom.Lookup(name) != atom.P {
+				continue
+			}
+			if tt == html.StartTagToken {
+				depth++
+				continue
+			}
+			if depth--; depth == 0 {
+				return collapseSpaces(textBuilder.String()), nil
+			}
+		}
+	}
+}
+
+// htmlText returns text content of utf-8 encoded HTML text b, with whitespace
+// collapsed.
+func htmlText(b []byte) (string, error) {
+	z := html.NewTokenizer(bytes.NewReader(b))
+	var textBuilder strings.Builder
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return collapseSpaces(textBuilder.String()), nil
+			}
+			return "", z.Err()
+		case html.TextToken:
+			textBuilder.Write(z.Text())
+		}
+	}
+}
+
+func collapseSpaces(s string) string { return strings.Join(strings.Fields(s), " ") }
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func Test_splitSummary(t *testing.T) {
+	const src = "Intro *text*.\n\n<!--more-->\n\nThe rest.\n"
+	summary, body := splitSummary([]byte(src))
+	if got, want := string(summary), "Intro *text*.\n\n"; got != want {
+		t.Errorf("got summary %q, want %q", got, want)
+	}
+	if got, want := string(body), "Intro *text*.\n\n\nThe rest.\n"; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+	for _, src := range []string{
+		"No marker",
+		"Use the divider like `<!--more-->` here.\n",
+		"Example:\n\n```markdown\nIntro\n<!--more-->\n```\n",
+		"Example:\n\n    <!--more-->\n",
+	} {
+		if summary, body := splitSummary([]byte(src)); summary != nil || string(body) != src {
+			t.Errorf("%q: got summary %q and body %q, want text as is", src, summary, body)
+		}
+	}
+	summary, _ = splitSummary([]byte("~~~~\n<!--more-->\n~~~\n~~~~\nIntro\n<!--more-->\nRest\n"))
+	if got, want := string(summary), "~~~~\n<!--more-->\n~~~\n~~~~\nIntro\n"; got != want {
+		t.Errorf("got summary %q after code block, want %q", got, want)
+	}
+}
+
+func Test_firstParagraph(t *testing.T) {
+	const src = "<h1>Title</h1>\n<p>First\n<em>paragraph</em>.</p>\n<p>Second</p>"
+	got, err := firstParagraph([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "First paragraph."; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"time"
+
+	anchor "github.com/shurcooL/sanitized_anchor_name"
+)
+
+// collectTags returns pages from all directories grouped by their tags.
+// Pages for each tag are ordered according to the -sort flag.
+func (b *builder) collectTags() map[string][]pageMeta {
+	tags := make(map[string][]pageMeta)
+	for _, dir := range slices.Sorted(maps.Keys(b.dirsIndex)) {
+		for _, meta := range b.dirsIndex[dir].pages {
+			for _, tag := range meta.Tags {
+				tags[tag] = append(tags[tag], meta)
+			}
+		}
+	}
+	for _, pages := range tags {
+		sortPages(pages, b.args.Sort)
+	}
+	return tags
+}
+
+// renderTags writes the "tags/index.html" file listing all tags in the
+// destination directory, and a "tags/TAG/index.html" file for each tag,
+// listing pages with such tag. Tag index is rendered with "tags.html"
+// template, and each tag page with "tag.html", falling back to
+// "index.html" and then the default template. Tags whose names map to the
+// same directory name, such as "C++" and "C#", are reported as an error.
+func (b *builder) renderTags() error {
+	tags := b.site.Tags
+	if len(tags) == 0 {
+		return nil
+	}
+	dir := filepath.Join(b.args.OutputDir, tagsDir)
+	if _, ok := b.dirsIndex[dir]; ok {
+		return fmt.Errorf("cannot create tag pages: %q directory already has pages", tagsDir)
+	}
+	names := slices.Sorted(maps.Keys(tags))
+	slugs := make(map[string]string, len(names)) // tags keyed by their directory names
+	for _, tag := range names {
+		slug := anchor.Create(tag)
+		if slug == "" {
+			return fmt.Errorf("tag %q cannot be used as a directory name", tag)
+		}
+		if other, ok := slugs[slug]; ok {
+			return fmt.Errorf("tags %q and %q would both be written to %q directory", other, tag, tagsDir+"/"+slug)
+		}
+		slugs[slug] = tag
+	}
+	var categories []pageMeta
+	var newest time.Time // latest modification time of all tagged pages
+	for _, tag := range names {
+		tagDir := filepath.Join(dir, anchor.Create(tag))
+		categories = append(categories, pageMeta{
+			Title: tag,
+			Dst:   filepath.Base(tagDir),
+			URL:   b.url(tagDir, true),
+			dst:   tagDir,
+		})
+		pages := make([]pageMeta, len(tags[tag]))
+		var mtime time.Time
+		for i, meta := range tags[tag] {
+			pages[i] = meta
+			if m := meta.mtime(); m.After(mtime) {
+				mtime = m
+			}
+			// make links relative to the tag page directory
+			if rel, err := filepath.Rel(tagDir, meta.dst); err == nil {
+				pages[i].Dst = filepath.ToSlash(rel)
+			}
+		}
+		if mtime.After(newest) {
+			newest = mtime
+		}
+		page := &Page{
+			Title:       tag,
+			URL:         b.url(tagDir, true),
+			ModTime:     mtime,
+			Site:        b.siteFor(tagDir),
+			Breadcrumbs: b.breadcrumbs(dir),
+			Pages:       pages,
+		}
+		if err := b.renderTagPage(tagDir, page, tagTemplate); err != nil {
+			return err
+		}
+	}
+	page := &Page{
+		Title:       fileNameToTitle(tagsDir),
+		URL:         b.url(dir, true),
+		ModTime:     newest,
+		Site:        b.siteFor(dir),
+		Breadcrumbs: b.breadcrumbs(b.args.OutputDir),
+		Categories:  categories,
+	}
+	return b.renderTagPage(dir, page, tagsTemplate)
+}
+
+// renderTagPage writes page to the index.html file in the destination
+// directory dir, using template with the given name if it exists, or the one
+// picked for the source root as for its index (see templateSet.pick). Page
+// LastMod is set to the latest of its ModTime and the template modification
+// time.
+func (b *builder) renderTagPage(dir string, page *Page, name string) error {
+	tpl, mtime := b.tpls.lookup(name), b.tpls.mtime
+	if tpl == nil {
+		var err error
+		if tpl, mtime, err = b.tpls.pick(b.args.InputDir, nil, indexTemplate); err != nil {
+			return err
+		}
+	}
+	page.LastMod = mtime
+	if page.ModTime.After(mtime) {
+		page.LastMod = page.ModTime
+	}
+	out := new(bytes.Buffer)
+	if err := tpl.Execute(out, page); err != nil {
+		return err
+	}
+	return b.writeFile(filepath.Join(dir, "index.html"), out.Bytes())
+}
+
+const (
+	tagsDir      = "tags"      // destination directory for tag pages
+	tagsTemplate = "tags.html" // used to render list of all tags
+	tagTemplate  = "tag.html"  // used to render list of pages with a given tag
+)
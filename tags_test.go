@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func Test_builder_collectTags(t *testing.T) {
+	b := &builder{
+		args: runArgs{Sort: "title"},
+		dirsIndex: map[string]*dirIndex{
+			"a": {pages: []pageMeta{{Title: "Zeta", Tags: []string{"go", "howto"}}}},
+			"b": {pages: []pageMeta{{Title: "Alpha", Tags: []string{"go"}}, {Title: "Untagged"}}},
+		},
+	}
+	tags := b.collectTags()
+	if len(tags) != 2 || len(tags["howto"]) != 1 {
+		t.Fatalf("unexpected tags: %+v", tags)
+	}
+	if got := tags["go"]; len(got) != 2 || got[0].Title != "Alpha" || got[1].Title != "Zeta" {
+		t.Fatalf("pages for tag are not ordered: %+v", got)
+	}
+}
+
+func Test_builder_renderTags_collision(t *testing.T) {
+	for _, tags := range [][]string{{"C++", "C#"}, {"Go", "go"}} {
+		b := &builder{
+			args: runArgs{OutputDir: t.TempDir()},
+			site: &Site{Tags: map[string][]pageMeta{
+				tags[0]: {{Title: "One"}},
+				tags[1]: {{Title: "Two"}},
+			}},
+			dirsIndex: make(map[string]*dirIndex),
+		}
+		err := b.renderTags()
+		if err == nil || !strings.Contains(err.Error(), tags[0]) || !strings.Contains(err.Error(), tags[1]) {
+			t.Errorf("tags %q: got error %v, want one naming both tags", tags, err)
+		}
+	}
+}
+
+func Test_builder_renderTagPage(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, dirTemplateName), []byte("dir"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	tpls, err := loadTemplates(fstest.MapFS{
+		"default.html": {Data: []byte("default")},
+		tagTemplate:    {Data: []byte("tag")},
+	}, src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	b := &builder{
+		args:    runArgs{InputDir: src, OutputDir: out},
+		tpls:    tpls,
+		outputs: make(map[string]struct{}),
+	}
+	for name, want := range map[string]string{tagTemplate: "tag", tagsTemplate: "dir"} {
+		dir := filepath.Join(out, name)
+		if err := b.renderTagPage(dir, &Page{}, name); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func Test_builder_renderTags(t *testing.T) {
+	out := t.TempDir()
+	tpls, err := loadTemplates(fstest.MapFS{
+		"default.html": {Data: []byte(`{{.URL}} {{dateFormat "2006-01-02" .LastMod}}`)},
+	}, t.TempDir(), templateFuncs(runArgs{}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) *mdSource { return &mdSource{mtime: time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC)} }
+	b := &builder{
+		args:      runArgs{OutputDir: out, BaseURL: "/docs/"},
+		tpls:      tpls,
+		site:      &Site{Tags: map[string][]pageMeta{"go": {{src: day(1)}, {src: day(3)}}, "c": {{src: day(2)}}}},
+		dirsIndex: make(map[string]*dirIndex),
+		navTree:   &treeNode{IsDir: true, dst: out},
+		outputs:   make(map[string]struct{}),
+	}
+	if err := b.renderTags(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"tags/index.html":    "/docs/tags/ 2024-05-03",
+		"tags/go/index.html": "/docs/tags/go/ 2024-05-03",
+		"tags/c/index.html":  "/docs/tags/c/ 2024-05-02",
+	} {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
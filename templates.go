@@ -0,0 +1,358 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template/parse"
+	"time"
+)
+
+// templateSet is a collection of templates parsed from the templates
+// directory. Each *.html file is available as a template named after this
+// file. Templates from the "partials" subdirectory are available to all other
+// templates.
+//
+// If the templates directory has "baseof.html" file, every other *.html file
+// there is a layout: it is parsed separately, on top of the base template and
+// partials, and may override blocks of the base template. Rendering such
+// layout executes the base template.
+//
+// Source directories may override templates for their subtree with
+// "_template.html" file, such templates are parsed on demand and can use any
+// template defined in the templates directory, or act as a layout if there's a
+// base template.
+type templateSet struct {
+	tpl     *template.Template
+	layouts map[string]*template.Template // layouts keyed by their file names, nil if there's no base template
+	def     *template.Template            // template used when no better match is found
+	scodes  *template.Template            // shortcode templates with partials, nil if there are none
+	base    *template.Template            // never executed copy of tpl to derive per-directory templates from
+	mtime   time.Time                     // latest modification time of template files
+
+	root    string                  // source directory root
+	dirs    map[string]*dirTemplate // per-directory templates, keyed by source directory
+	commits map[string]*gitCommit   // last commits of source files, see gitCommits
+}
+
+// dirTemplate is a template from the "_template.html" file in one of the
+// source directories.
+type dirTemplate struct {
+	tpl   *template.Template
+	mtime time.Time
+}
+
+// loadTemplates parses *.html files from fsys. Default template is the one
+// from the "default.html" file, or the first one in lexical order if there's
+// no such file, not counting templates of special pages (see roleTemplates). Per-directory templates are looked up within the root
+// directory. Functions from funcs are available to all templates.
+func loadTemplates(fsys fs.FS, root string, funcs template.FuncMap) (*templateSet, error) {
+	names, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	partials, err := fs.Glob(fsys, partialsDir+"/*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range partials {
+		if slices.Contains(names, path.Base(name)) {
+			return nil, fmt.Errorf("partial %q has the same name as %q template", name, path.Base(name))
+		}
+	}
+	withBase := slices.Contains(names, baseTemplate)
+	names = slices.DeleteFunc(names, func(name string) bool { return name == baseTemplate })
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no *.html files found")
+	}
+	base := template.New("").Funcs(funcs)
+	if len(partials) != 0 {
+		if base, err = base.ParseFS(fsys, partials...); err != nil {
+			return nil, err
+		}
+	}
+	ts := &templateSet{
+		root: root,
+		dirs: make(map[string]*dirTemplate),
+	}
+	if withBase {
+		if base, err = base.ParseFS(fsys, baseTemplate); err != nil {
+			return nil, err
+		}
+		ts.base = base
+		ts.layouts = make(map[string]*template.Template, len(names))
+		for _, name := range names {
+			b, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			if ts.layouts[name], err = ts.layout(name, string(b)); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if base, err = base.ParseFS(fsys, names...); err != nil {
+			return nil, err
+		}
+		if err := checkTemplateRefs(base); err != nil {
+			return nil, err
+		}
+		ts.base = base
+		// html/template forbids cloning templates once they were executed
+		if ts.tpl, err = base.Clone(); err != nil {
+			return nil, err
+		}
+	}
+	if ts.def = ts.lookup(defaultTemplate); ts.def == nil {
+		// templates only used for special pages cannot be the default one
+		i := slices.IndexFunc(names, func(name string) bool { return !slices.Contains(roleTemplates, name) })
+		if i < 0 {
+			return nil, fmt.Errorf("no default template: only %s files found", strings.Join(names, ", "))
+		}
+		ts.def = ts.lookup(names[i])
+	}
+	if ts.scodes, err = loadShortcodes(fsys, partials, funcs); err != nil {
+		return nil, err
+	}
+	if ts.mtime, err = templatesMtime(fsys, "", nil); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// templatesMtime returns the latest modification time of template files in
+// fsys, including partials and shortcodes. For files tracked by git, dates of
+// their last commits from commits are used instead of file modification times;
+// commits are keyed by file paths joined with dir, see readGitRepo.
+func templatesMtime(fsys fs.FS, dir string, commits map[string]*gitCommit) (time.Time, error) {
+	var mtime time.Time
+	for _, pat := range []string{"*.html", partialsDir + "/*.html", shortcodesDir + "/*.html"} {
+		names, err := fs.Glob(fsys, pat)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, name := range names {
+			var m time.Time
+			if c := commits[filepath.Join(dir, filepath.FromSlash(name))]; c != nil {
+				m = c.Date
+			} else {
+				fi, err := fs.Stat(fsys, name)
+				if err != nil {
+					return time.Time{}, err
+				}
+				m = fi.ModTime()
+			}
+			if m.After(mtime) {
+				mtime = m
+			}
+		}
+	}
+	return mtime, nil
+}
+
+// loadShortcodes parses templates from the "shortcodes" subdirectory of fsys,
+// together with the partials. It returns nil if there are no shortcode
+// templates.
+func loadShortcodes(fsys fs.FS, partials []string, funcs template.FuncMap) (*template.Template, error) {
+	names, err := fs.Glob(fsys, shortcodesDir+"/*.html")
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	for _, name := range partials {
+		if slices.ContainsFunc(names, func(s string) bool { return path.Base(s) == path.Base(name) }) {
+			return nil, fmt.Errorf("partial %q has the same name as a shortcode", name)
+		}
+	}
+	tpl, err := template.New("").Funcs(funcs).ParseFS(fsys, append(names, partials...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTemplateRefs(tpl); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// shortcode renders shortcode sc with the template of the same name.
+func (ts *templateSet) shortcode(sc *shortcode) (string, error) {
+	var tpl *template.Template
+	if ts.scodes != nil {
+		tpl = ts.scodes.Lookup(sc.Name + htmlSuffix)
+	}
+	if tpl == nil {
+		return "", fmt.Errorf("template %q not found", path.Join(shortcodesDir, sc.Name+htmlSuffix))
+	}
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, sc); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// lookup returns template or layout with the given name, or nil if there's no
+// such template.
+func (ts *templateSet) lookup(name string) *template.Template {
+	if ts.layouts != nil {
+		return ts.layouts[name]
+	}
+	return ts.tpl.Lookup(name)
+}
+
+// layout parses text as a template with the given name on top of the never
+// executed base set, and returns template to execute: the base template if
+// set has one, or the newly parsed one otherwise.
+func (ts *templateSet) layout(name, text string) (*template.Template, error) {
+	tpl, err := ts.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tpl.New(name).Parse(text); err != nil {
+		return nil, err
+	}
+	if err := checkTemplateRefs(tpl); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if ts.layouts != nil {
+		return tpl.Lookup(baseTemplate), nil
+	}
+	return tpl.Lookup(name), nil
+}
+
+// checkTemplateRefs verifies that all templates and blocks referenced by
+// {{template}} actions of the set tpl belongs to are defined.
+func checkTemplateRefs(tpl *template.Template) error {
+	for _, t := range tpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		var err error
+		walkTemplateNodes(t.Tree.Root, func(name string) {
+			if err != nil {
+				return
+			}
+			if ref := tpl.Lookup(name); ref == nil || ref.Tree == nil {
+				err = fmt.Errorf("template %q used by %q is not defined", name, t.Name())
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTemplateNodes calls fn with the name of each template invoked within
+// node and its descendants.
+func walkTemplateNodes(node parse.Node, fn func(name string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, n := range n.Nodes {
+			walkTemplateNodes(n, fn)
+		}
+	case *parse.TemplateNode:
+		fn(n.Name)
+	case *parse.IfNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	}
+}
+
+// pick returns template to render a page from the source directory dir, along
+// with the latest modification time of files this template came from. If page
+// front matter has "template" key, template with such name must exist.
+// Otherwise the template from the nearest "_template.html" file is used, if
+// there's one in dir or any of its parents. Otherwise the first existing
+// template from names is used, falling back to the default one.
+func (ts *templateSet) pick(dir string, params map[string]any, names ...string) (*template.Template, time.Time, error) {
+	if name := paramString(params, "template"); name != "" {
+		if !strings.HasSuffix(name, htmlSuffix) {
+			name += htmlSuffix
+		}
+		if t := ts.lookup(name); t != nil {
+			return t, ts.mtime, nil
+		}
+		return nil, time.Time{}, fmt.Errorf("template %q not found", name)
+	}
+	dt, err := ts.dirTemplate(dir)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if dt != nil {
+		mtime := ts.mtime
+		if dt.mtime.After(mtime) {
+			mtime = dt.mtime
+		}
+		return dt.tpl, mtime, nil
+	}
+	for _, name := range names {
+		if t := ts.lookup(name); t != nil {
+			return t, ts.mtime, nil
+		}
+	}
+	return ts.def, ts.mtime, nil
+}
+
+// dirTemplate returns template from the "_template.html" file in the source
+// directory dir, or the nearest of its parents up to the source root. If no
+// such file exists, it returns nil.
+func (ts *templateSet) dirTemplate(dir string) (*dirTemplate, error) {
+	if dt, ok := ts.dirs[dir]; ok {
+		return dt, nil
+	}
+	name := filepath.Join(dir, dirTemplateName)
+	b, err := os.ReadFile(name)
+	switch {
+	case err == nil:
+		tpl, err := ts.layout(name, string(b))
+		if err != nil {
+			return nil, err
+		}
+		dt := &dirTemplate{tpl: tpl}
+		if c := ts.commits[name]; c != nil {
+			dt.mtime = c.Date
+		} else if fi, err := os.Stat(name); err == nil {
+			dt.mtime = fi.ModTime()
+		}
+		ts.dirs[dir] = dt
+		return dt, nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	var dt *dirTemplate
+	if parent := filepath.Dir(dir); dir != ts.root && parent != dir {
+		if dt, err = ts.dirTemplate(parent); err != nil {
+			return nil, err
+		}
+	}
+	ts.dirs[dir] = dt
+	return dt, nil
+}
+
+const (
+	defaultTemplate = "default.html"
+	baseTemplate    = "baseof.html" // base layout with blocks other templates override
+	pageTemplate    = "page.html"   // used to render pages
+	indexTemplate   = "index.html"  // used to render directory indexes
+
+	dirTemplateName = "_template.html" // per-directory template in source tree
+	partialsDir     = "partials"       // templates subdirectory with templates shared by all others
+)
+
+// roleTemplates are templates only used for special pages, they are never
+// picked as the default template
+var roleTemplates = []string{notFoundTemplate, tagTemplate, tagsTemplate}
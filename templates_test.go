@@ -0,0 +1,183 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func Test_templateSet_pick(t *testing.T) {
+	fsys := fstest.MapFS{
+		"default.html": {Data: []byte(`default`)},
+		"index.html":   {Data: []byte(`index`)},
+		"wide.html":    {Data: []byte(`wide`)},
+	}
+	root := t.TempDir()
+	blog := filepath.Join(root, "blog")
+	if err := os.MkdirAll(filepath.Join(blog, "2024"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(blog, dirTemplateName), []byte(`blog`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	tpls, err := loadTemplates(fsys, root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// per-directory templates must be available after some other templates
+	// were already executed
+	if err := tpls.def.Execute(io.Discard, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		dir    string
+		params map[string]any
+		names  []string
+		want   string
+	}{
+		{root, nil, []string{pageTemplate}, "default.html"},
+		{root, nil, []string{indexTemplate}, "index.html"},
+		{root, map[string]any{"template": "wide"}, []string{indexTemplate}, "wide.html"},
+		{root, map[string]any{"template": "wide.html"}, nil, "wide.html"},
+		{blog, nil, []string{indexTemplate}, filepath.Join(blog, dirTemplateName)},
+		{filepath.Join(blog, "2024"), nil, []string{pageTemplate}, filepath.Join(blog, dirTemplateName)},
+		{filepath.Join(blog, "2024"), map[string]any{"template": "wide"}, nil, "wide.html"},
+	} {
+		tpl, _, err := tpls.pick(tc.dir, tc.params, tc.names...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tpl.Name(); got != tc.want {
+			t.Errorf("pick(%q, %v, %q): got %q, want %q", tc.dir, tc.params, tc.names, got, tc.want)
+		}
+	}
+	if _, _, err := tpls.pick(root, map[string]any{"template": "missing"}); err == nil {
+		t.Fatal("picking non-existent template should fail")
+	}
+}
+
+func Test_loadTemplates_builtin(t *testing.T) {
+	tpls, err := loadTemplates(exampleTemplateFS, t.TempDir(), templateFuncs(runArgs{}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpls.def == nil {
+		t.Fatal("no default template")
+	}
+}
+
+func Test_loadTemplates_layouts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"baseof.html":          {Data: []byte(`{{template "header.html"}}|{{block "main" .}}none{{end}}`)},
+		"partials/header.html": {Data: []byte(`header`)},
+		"page.html":            {Data: []byte(`{{define "main"}}page {{.}}{{end}}`)},
+		"index.html":           {Data: []byte(`{{define "main"}}index {{.}}{{end}}`)},
+		"tag.html":             {Data: []byte(``)},
+	}
+	tpls, err := loadTemplates(fsys, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		pageTemplate:  "header|page x",
+		indexTemplate: "header|index x",
+		tagTemplate:   "header|none",
+	} {
+		tpl, _, err := tpls.pick(tpls.root, nil, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sb strings.Builder
+		if err := tpl.Execute(&sb, "x"); err != nil {
+			t.Fatal(err)
+		}
+		if got := sb.String(); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func Test_loadTemplates_missing(t *testing.T) {
+	for _, fsys := range []fstest.MapFS{
+		{
+			"baseof.html": {Data: []byte(`{{template "main" .}}`)},
+			"page.html":   {Data: []byte(`{{define "main"}}page{{end}}`)},
+			"index.html":  {Data: []byte(`{{define "content"}}index{{end}}`)},
+		},
+		{
+			"page.html": {Data: []byte(`{{template "header.html"}}`)},
+		},
+	} {
+		_, err := loadTemplates(fsys, t.TempDir(), nil)
+		if err == nil {
+			t.Fatal("loading templates with undefined references should fail")
+		}
+		t.Log(err)
+	}
+}
+
+func Test_templatesMtime(t *testing.T) {
+	now := time.Now()
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"default.html":            {ModTime: now},
+		"partials/header.html":    {ModTime: now},
+		"shortcodes/figure.html":  {ModTime: now},
+		"partials/untracked.html": {ModTime: old.AddDate(1, 0, 0)},
+	}
+	commits := map[string]*gitCommit{
+		filepath.Join("tpl", "default.html"):              {Date: old},
+		filepath.Join("tpl", "partials", "header.html"):   {Date: old},
+		filepath.Join("tpl", "shortcodes", "figure.html"): {Date: old.AddDate(0, 1, 0)},
+	}
+	got, err := templatesMtime(fsys, "tpl", commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := old.AddDate(1, 0, 0); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, _ := templatesMtime(fsys, "tpl", nil); !got.Equal(now) {
+		t.Fatalf("without commits got %v, want %v", got, now)
+	}
+}
+
+func Test_loadTemplates_roleTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"404.html":  {Data: []byte(`NOTFOUND`)},
+		"main.html": {Data: []byte(`main`)},
+	}
+	tpls, err := loadTemplates(fsys, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{pageTemplate, indexTemplate} {
+		tpl, _, err := tpls.pick(tpls.root, nil, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tpl.Name(); got != "main.html" {
+			t.Errorf("%s: got %q template, want main.html", name, got)
+		}
+	}
+	fsys["baseof.html"] = &fstest.MapFile{Data: []byte(`{{block "main" .}}{{end}}`)}
+	fsys["main.html"] = &fstest.MapFile{Data: []byte(`{{define "main"}}main{{end}}`)}
+	fsys["404.html"] = &fstest.MapFile{Data: []byte(`{{define "main"}}NOTFOUND{{end}}`)}
+	if tpls, err = loadTemplates(fsys, t.TempDir(), nil); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := tpls.def.Execute(&sb, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); got != "main" {
+		t.Errorf("with base layout got %q, want %q", got, "main")
+	}
+	if _, err := loadTemplates(fstest.MapFS{"404.html": {}}, t.TempDir(), nil); err == nil {
+		t.Error("loading only special page templates should fail")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// treeNode is an element of the site navigation tree: either a page, or a
+// directory with its pages and subdirectories as children.
+type treeNode struct {
+	Title    string
+	URL      string      // URL relative to the site root, see relURL template function
+	IsDir    bool        // whether node is a directory
+	Current  bool        // whether node is the page being rendered
+	Ancestor bool        // whether node is a directory containing the page being rendered
+	Children []*treeNode // pages first, then subdirectories
+
+	dst string // destination file or directory path
+}
+
+// tree builds navigation tree starting from the destination directory dir.
+// Directory README pages are not included as separate nodes, as they're
+// represented by their directory nodes.
+func (b *builder) tree(dir string) *treeNode {
+	node := &treeNode{
+		Title: b.dirTitle(dir),
+		URL:   b.url(dir, true),
+		IsDir: true,
+		dst:   dir,
+	}
+	res, ok := b.dirsIndex[dir]
+	if !ok {
+		return node
+	}
+	readme := res.readme(b.args.SuffixHTML)
+	for _, meta := range res.pages {
+		if readme != nil && meta.dst == readme.dst {
+			continue
+		}
+		node.Children = append(node.Children, &treeNode{Title: meta.Title, URL: meta.URL, dst: meta.dst})
+	}
+	for _, meta := range res.categories {
+		node.Children = append(node.Children, b.tree(meta.dst))
+	}
+	return node
+}
+
+// mark returns a copy of tree with the node for destination path dst flagged
+// as current, and its parent directories flagged as ancestors. Subtrees not
+// leading to dst are shared with the original tree.
+func (n *treeNode) mark(dst string) *treeNode {
+	if n.dst == dst {
+		c := *n
+		c.Current = true
+		return &c
+	}
+	if !n.IsDir || !strings.HasPrefix(dst, n.dst+string(filepath.Separator)) {
+		return n
+	}
+	c := *n
+	c.Ancestor = true
+	c.Children = make([]*treeNode, len(n.Children))
+	for i, child := range n.Children {
+		c.Children[i] = child.mark(dst)
+	}
+	return &c
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func Test_treeNode_mark(t *testing.T) {
+	root := &treeNode{IsDir: true, dst: "out", Children: []*treeNode{
+		{dst: filepath.Join("out", "a.md")},
+		{IsDir: true, dst: filepath.Join("out", "sub"), Children: []*treeNode{
+			{dst: filepath.Join("out", "sub", "b.md")},
+		}},
+		{IsDir: true, dst: filepath.Join("out", "other")},
+	}}
+	got := root.mark(filepath.Join("out", "sub", "b.md"))
+	if !got.Ancestor || !got.Children[1].Ancestor || !got.Children[1].Children[0].Current {
+		t.Fatal("path to the current page is not marked")
+	}
+	if got.Children[0].Current || got.Children[2].Ancestor {
+		t.Fatal("unrelated nodes are marked")
+	}
+	if got.Children[2] != root.Children[2] {
+		t.Error("unrelated subtree should be shared with the original tree")
+	}
+	if root.Ancestor || root.Children[1].Children[0].Current {
+		t.Fatal("original tree was modified")
+	}
+}